@@ -1,6 +1,14 @@
 // config/config.go
 package config
 
+import (
+	"time"
+
+	"github.com/mssql_ie/masking"
+	"github.com/mssql_ie/sqlparams"
+	"github.com/mssql_ie/transforms"
+)
+
 // DBConfig 数据库连接配置
 type DBConfig struct {
 	Server   string
@@ -9,8 +17,34 @@ type DBConfig struct {
 	Password string
 	DBName   string
 	Encrypt  string
-	Charset  string
 	Timeout  uint64
+	Profile  string // 使用的连接配置名，未使用时为空
+	// DSN 完整的连接字符串（sqlserver:// 或 ADO 格式），其余非空字段覆盖其中的同名参数
+	DSN string
+	// Params 透传给驱动的连接参数，键名与 ADO 连接字符串相同，优先级最高
+	Params map[string]string
+	// Retry 建立连接时的重试策略
+	Retry RetryConfig
+	// 连接池设置：最大打开连接数和时间为 0 时不限制，最大空闲连接数为 0 时不保留空闲连接
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// SessionSet 在每个新建或从连接池取出的连接上执行的 SET 语句
+	SessionSet []string
+}
+
+// RetryConfig 暂时性错误（死锁、故障转移、连接中断等）的重试策略
+type RetryConfig struct {
+	Retries int           // 最大重试次数，0 表示不重试
+	Backoff time.Duration // 首次重试前的等待时间，之后每次翻倍
+}
+
+// HookConfig 前置/后置SQL配置
+type HookConfig struct {
+	PreSQL        []string // 导入导出前执行的SQL批次
+	PostSQL       []string // 导入导出后执行的SQL批次
+	PostSQLAlways bool     // 失败时也执行后置SQL
 }
 
 // ExportConfig 导出配置
@@ -23,6 +57,48 @@ type ExportConfig struct {
 	Limit        int
 	BinaryFormat string
 	FileCharset  string
+	Hooks        HookConfig
+	// Tables 多表导出时的表匹配模式（如 dbo.*），与 Table/SQL 互斥
+	Tables []string
+	// ExcludeTables 多表导出时需要排除的表匹配模式
+	ExcludeTables []string
+	// OutDir 多表导出时的输出目录
+	OutDir string
+	// Transforms 写入CSV前按列应用的值转换
+	Transforms transforms.Set
+	// Masks 写入CSV前按列应用的脱敏规则，在值转换之后执行
+	Masks *masking.Rules
+	// Passphrase 非空时使用该口令加密输出文件
+	Passphrase string
+	// Manifest 导出完成后在数据文件旁写入 .manifest.json 清单
+	Manifest bool
+	// Server、Database 记录到清单中的源服务器和数据库
+	Server   string
+	Database string
+	// Retry 导出查询遇到暂时性错误时的重试策略，重试时从头重新导出文件；前后置SQL和脚本准备批次不参与重试，
+	// 存储过程可能有副作用，不重试
+	Retry RetryConfig
+	// Isolation 导出事务的隔离级别 {read-uncommitted, read-committed, snapshot, repeatable-read}，
+	// 为空时不开启事务，使用会话的隔离级别；多表导出使用 snapshot 时所有表在同一个快照中导出
+	Isolation string
+	// NoLock 表导出时添加 WITH (NOLOCK) 提示
+	NoLock bool
+	// Columns 单表导出时导出的列（按指定顺序），为空时导出所有列
+	Columns []string
+	// ExcludeColumns 单表导出时排除的列
+	ExcludeColumns []string
+	// Where 单表导出的过滤条件（SQL 谓词）
+	Where string
+	// OrderBy 单表导出的排序列，如 "created_at desc, id"
+	OrderBy string
+	// Params 自定义SQL或存储过程中 @名称 引用的类型化参数，通过 sp_executesql 绑定
+	Params []sqlparams.Param
+	// Proc 要执行的存储过程，每个结果集导出为单独的文件，与 Table/SQL 互斥
+	Proc string
+	// OutParams 存储过程的输出参数，其值与返回值一起输出并记录到清单中
+	OutParams []sqlparams.Param
+	// SetupSQL SQL 脚本中导出查询之前的批次，在 --pre-sql 之后、同一会话中执行（如创建临时表）
+	SetupSQL []string
 }
 
 // ImportConfig 导入配置
@@ -36,4 +112,79 @@ type ImportConfig struct {
 	SkipErrors   bool
 	BinaryFormat string
 	FileCharset  string
+	Atomic       bool // 整个导入（含清空表）在单个事务中完成
+	Hooks        HookConfig
+	// SourceFileColumn 记录来源文件名的表列，为空时不记录
+	SourceFileColumn string
+	// ArchiveDir 导入成功后文件移动到的归档目录，为空时不移动
+	ArchiveDir string
+	// Transforms 类型转换前按列应用的值转换
+	Transforms transforms.Set
+	// Passphrase 解密加密输入文件的口令
+	Passphrase string
+	// VerifyManifest 导入前按 .manifest.json 清单校验文件的校验和与行数
+	VerifyManifest bool
+	// Reconcile 导入后核对插入行数与表行数的增量
+	Reconcile bool
+	// ReconcileChecksum 导入后额外核对表中新增数据与文件的校验和
+	ReconcileChecksum bool
+	// OnOverflow 值超出列长度或精度时的处理策略 {error, truncate, reject}，为空时等同 error
+	OnOverflow string
+	// RejectPath 被拒绝行的输出文件，配合 OnOverflow=reject 使用
+	RejectPath string
+	// DryRun 只校验文件（解析、列匹配、类型转换），不执行任何 INSERT 或 TRUNCATE
+	DryRun bool
+	// Strict 校验时额外检查长度、精度、非空以及主键/唯一键重复
+	Strict bool
+	// ReportPath 校验报告输出路径，为空时只输出汇总信息
+	ReportPath string
+	// Retry 插入批次遇到暂时性错误时的重试策略，重试时回滚并重新执行当前批次
+	Retry RetryConfig
+}
+
+// DumpConfig 整库转储配置
+type DumpConfig struct {
+	OutDir        string
+	Tables        []string // 表匹配模式，为空时转储所有表
+	ExcludeTables []string
+	Server        string // 记录到清单中的源服务器
+	Database      string // 记录到清单中的源数据库
+	Retry         RetryConfig
+	Isolation     string // 导出数据的隔离级别，snapshot 时所有表在同一个快照中导出
+}
+
+// RestoreConfig 整库恢复配置
+type RestoreConfig struct {
+	InDir      string
+	Batch      int
+	SkipErrors bool
+	Retry      RetryConfig
+}
+
+// CopyConfig 跨库复制配置
+type CopyConfig struct {
+	Table       string // 源表名，与 SQL 互斥
+	SQL         string // 源查询，与 Table 互斥
+	TargetTable string // 目标表名，为空时与源表同名
+	CreateTable bool   // 目标表不存在时按源表结构创建
+	SchemaFile  string // 自动建表时使用的 JSON 表结构文档，为空时读取源表结构
+	Truncate    bool
+	Batch       int
+	SkipErrors  bool
+	Bulk        bool // 使用批量复制协议写入
+}
+
+// DiffConfig 数据比较配置
+type DiffConfig struct {
+	Table        string   // 基准表
+	CSVPath      string   // 与基准表比较的CSV文件，与 TargetTable 互斥
+	TargetTable  string   // 与基准表比较的表，与 CSVPath 互斥
+	Keys         []string // 键列，为空时使用主键
+	ChunkSize    int      // 每个分块的大致行数
+	Header       bool
+	Delimiter    rune
+	BinaryFormat string
+	FileCharset  string
+	Output       string // 差异输出文件，为空时输出到标准输出
+	Format       string // 输出格式 csv 或 json
 }
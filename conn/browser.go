@@ -0,0 +1,95 @@
+// conn/browser.go
+package conn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// browserPort SQL Server Browser 服务的 UDP 端口
+const browserPort = 1434
+
+// browserTimeout 未指定 --timeout 时等待 SQL Server Browser 响应的超时时间
+const browserTimeout = 5 * time.Second
+
+// SQL Server Browser 协议（SSRP）消息类型
+const (
+	clntUcastInst = 0x04 // 查询指定实例
+	svrResp       = 0x05 // 服务器响应
+)
+
+// lookupInstancePort 通过 SQL Server Browser 查询命名实例的 TCP 端口
+// 连接时的端口解析由驱动完成，这里仅用于在连接失败时给出具体原因
+func lookupInstancePort(host, instance string, port int, timeout time.Duration) (uint64, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := net.DialTimeout("udp", addr, timeout)
+	if err != nil {
+		return 0, fmt.Errorf("连接 SQL Server Browser %s 失败: %w", addr, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	req := append([]byte{clntUcastInst}, instance...)
+	if _, err := conn.Write(append(req, 0)); err != nil {
+		return 0, fmt.Errorf("向 SQL Server Browser %s 发送请求失败: %w", addr, err)
+	}
+
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return 0, fmt.Errorf("SQL Server Browser %s 无响应（请确认 SQL Server Browser 服务已启动且 UDP %d 端口可访问，或直接指定 --port）: %w", addr, port, err)
+	}
+
+	instances, err := parseBrowserResponse(buf[:n])
+	if err != nil {
+		return 0, fmt.Errorf("解析 SQL Server Browser 响应失败: %w", err)
+	}
+	for _, inst := range instances {
+		if !strings.EqualFold(inst["InstanceName"], instance) {
+			continue
+		}
+		tcp := inst["tcp"]
+		if tcp == "" {
+			return 0, fmt.Errorf("实例 %s\\%s 未启用 TCP/IP 协议", host, instance)
+		}
+		p, err := strconv.ParseUint(tcp, 10, 16)
+		if err != nil {
+			return 0, fmt.Errorf("实例 %s\\%s 的端口 %s 无效", host, instance, tcp)
+		}
+		return p, nil
+	}
+	return 0, fmt.Errorf("服务器 %s 上不存在实例 %s", host, instance)
+}
+
+// parseBrowserResponse 解析 SVR_RESP 消息
+// 消息格式为 0x05、2 字节小端长度，以及形如 ServerName;X;InstanceName;Y;...;tcp;1433;; 的实例列表，
+// 各实例以 ;; 结尾
+func parseBrowserResponse(msg []byte) ([]map[string]string, error) {
+	if len(msg) < 3 || msg[0] != svrResp {
+		return nil, fmt.Errorf("无效的响应消息")
+	}
+	size := int(binary.LittleEndian.Uint16(msg[1:3]))
+	data := msg[3:]
+	if size < len(data) {
+		data = data[:size]
+	}
+
+	var instances []map[string]string
+	for _, record := range bytes.Split(data, []byte(";;")) {
+		fields := strings.Split(string(record), ";")
+		if len(fields) < 2 {
+			continue
+		}
+		inst := make(map[string]string, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			inst[fields[i]] = fields[i+1]
+		}
+		instances = append(instances, inst)
+	}
+	return instances, nil
+}
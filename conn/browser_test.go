@@ -0,0 +1,105 @@
+// conn/browser_test.go
+package conn
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// browserReply 构造 SVR_RESP 消息
+func browserReply(data string) []byte {
+	msg := []byte{svrResp, 0, 0}
+	binary.LittleEndian.PutUint16(msg[1:3], uint16(len(data)))
+	return append(msg, data...)
+}
+
+// startBrowser 启动本地 UDP 响应端模拟 SQL Server Browser，reply 为 nil 时不响应
+func startBrowser(t *testing.T, reply []byte) int {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听 UDP 失败: %v", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n == 0 || buf[0] != clntUcastInst || reply == nil {
+				continue
+			}
+			pc.WriteTo(reply, addr)
+		}
+	}()
+	return pc.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestLookupInstancePort(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   []byte
+		want    uint64
+		wantErr string
+	}{
+		{
+			name:  "找到实例",
+			reply: browserReply("ServerName;DB01;InstanceName;SQLEXPRESS;IsClustered;No;Version;15.0.2000.5;tcp;50123;;"),
+			want:  50123,
+		},
+		{
+			name:  "实例名不区分大小写",
+			reply: browserReply("ServerName;DB01;InstanceName;OTHER;tcp;1500;;ServerName;DB01;InstanceName;sqlexpress;tcp;1501;;"),
+			want:  1501,
+		},
+		{
+			name:    "实例不存在",
+			reply:   browserReply("ServerName;DB01;InstanceName;OTHER;IsClustered;No;Version;15.0.2000.5;tcp;50123;;"),
+			wantErr: "不存在实例",
+		},
+		{
+			name:    "未启用 TCP/IP",
+			reply:   browserReply("ServerName;DB01;InstanceName;SQLEXPRESS;IsClustered;No;Version;15.0.2000.5;np;\\\\DB01\\pipe\\sql\\query;;"),
+			wantErr: "未启用 TCP/IP",
+		},
+		{
+			name:    "响应类型错误",
+			reply:   []byte{0x06, 0x01, 0x00, 'x'},
+			wantErr: "解析 SQL Server Browser 响应失败",
+		},
+		{
+			name:    "响应过短",
+			reply:   []byte{svrResp, 0x01},
+			wantErr: "解析 SQL Server Browser 响应失败",
+		},
+		{
+			name:    "无响应",
+			wantErr: "无响应",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := startBrowser(t, tt.reply)
+			got, err := lookupInstancePort("127.0.0.1", "SQLEXPRESS", port, 200*time.Millisecond)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("期望错误包含 %q，实际为 %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("查询端口失败: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("端口为 %d，期望 %d", got, tt.want)
+			}
+		})
+	}
+}
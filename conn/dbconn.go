@@ -2,16 +2,19 @@
 package conn
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
-	_ "github.com/microsoft/go-mssqldb"
+	mssql "github.com/microsoft/go-mssqldb"
 	"github.com/microsoft/go-mssqldb/msdsn"
 
 	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/retry"
 )
 
 // Connect 建立并返回SQL Server数据库连接
@@ -24,68 +27,162 @@ func Connect(cfg config.DBConfig) (*sql.DB, error) {
 		return nil, fmt.Errorf("构建连接字符串失败: %w", err)
 	}
 
-	// 打开连接
-	db, err := sql.Open("mssql", connStr)
+	// 通过已注册的 mssql 驱动创建连接器（保留 ? 占位符支持），以便设置会话初始化语句
+	connector, err := openConnector(connStr)
 	if err != nil {
 		return nil, fmt.Errorf("创建连接失败: %w", err)
 	}
+	db := sql.OpenDB(connector)
 
-	// 测试连接
-	if err := db.Ping(); err != nil {
+	// 配置连接池
+	setConnPool(db, cfg)
+
+	// 测试连接，数据库正在启动或故障转移时按重试策略重试
+	ctx := context.Background()
+	err = retry.Do(ctx, cfg.Retry, "连接数据库", func() error { return db.PingContext(ctx) })
+	if err != nil {
 		db.Close()
+		// 命名实例连接失败时查询 SQL Server Browser，给出比驱动更具体的原因
+		if browserErr := diagnoseInstance(connStr, cfg); browserErr != nil {
+			return nil, fmt.Errorf("连接数据库失败: %w（%v）", browserErr, err)
+		}
 		return nil, fmt.Errorf("连接数据库失败: %w", err)
 	}
 
-	// 配置连接池
-	setConnPool(db)
+	// 会话设置在每个新建或从连接池取出的连接上执行；先在单独的连接上执行一次，
+	// 使语句错误以 SQL Server 的原始错误信息报告，而不是驱动的 bad connection
+	if sessionSQL := buildSessionSQL(cfg.SessionSet); sessionSQL != "" {
+		if err := checkSessionSQL(ctx, db, sessionSQL); err != nil {
+			db.Close()
+			return nil, err
+		}
+		connector.SessionInitSQL = sessionSQL
+	}
 
 	return db, nil
 }
 
+// openConnector 使用注册为 mssql 的驱动实例创建连接器
+func openConnector(connStr string) (*mssql.Connector, error) {
+	db, err := sql.Open("mssql", "")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	drv, ok := db.Driver().(*mssql.Driver)
+	if !ok {
+		return nil, fmt.Errorf("不支持的驱动类型 %T", db.Driver())
+	}
+	return drv.OpenConnector(connStr)
+}
+
+// buildSessionSQL 将 --session-set 指定的会话设置拼接为一个批次，未以 SET 开头的设置自动补全
+func buildSessionSQL(settings []string) string {
+	var stmts []string
+	for _, s := range settings {
+		s = strings.TrimSuffix(strings.TrimSpace(s), ";")
+		if s == "" {
+			continue
+		}
+		if fields := strings.Fields(s); !strings.EqualFold(fields[0], "SET") {
+			s = "SET " + s
+		}
+		stmts = append(stmts, s)
+	}
+	return strings.Join(stmts, ";\n")
+}
+
+// checkSessionSQL 在单独的连接上执行会话设置，校验语句是否有效
+func checkSessionSQL(ctx context.Context, db *sql.DB, sessionSQL string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, sessionSQL); err != nil {
+		return fmt.Errorf("执行会话设置失败: %w", err)
+	}
+	return nil
+}
+
+// diagnoseInstance 连接命名实例（未指定端口）失败时，查询 SQL Server Browser 检查实例是否可用，
+// 返回 Browser 无响应、实例不存在或未启用 TCP/IP 等具体原因；非命名实例或查询成功时返回 nil
+func diagnoseInstance(connStr string, cfg config.DBConfig) error {
+	p, err := msdsn.Parse(connStr)
+	if err != nil || p.Instance == "" || p.Port != 0 || slices.Contains(p.Protocols, "admin") {
+		return nil
+	}
+	timeout := browserTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	_, err = lookupInstancePort(p.Host, p.Instance, browserPort, timeout)
+	return err
+}
+
 // buildConnStr 构建SQL Server连接字符串
+// 以 --dsn 指定的连接字符串为基础，依次叠加各连接参数和 --conn-param 指定的参数，
+// 最终生成 ADO 格式的连接字符串。命名实例未指定端口时不写入端口，由驱动在每次建立连接时
+// 查询 SQL Server Browser，实例重启后端口变化也能连接
 func buildConnStr(cfg config.DBConfig) (string, error) {
-	/*
-		EncryptionOff      = 0
-		EncryptionRequired = 1
-		EncryptionDisabled = 3
-		EncryptionStrict   = 4
-	*/
-	encrypt := 3
-	switch strings.ToLower(cfg.Encrypt) {
-	case "off":
-		encrypt = 0
-	case "required":
-		encrypt = 1
-	case "disabled":
-		encrypt = 3
-	case "strict":
-		encrypt = 4
-	default:
-		encrypt = 3
-	}
-
-	mssqlConfig := msdsn.Config{
-		User:       cfg.User,
-		Password:   cfg.Password,
-		Port:       cfg.Port,
-		Database:   cfg.DBName,
-		Host:       cfg.Server,
-		Encryption: msdsn.Encryption(encrypt),
-	}
-	params := make(map[string]interface{})
-	params["protocol"] = "tcp"
-	query := url.Values{}
-	if len(cfg.Charset) > 0 {
-		query.Add("charset", cfg.Charset)
-
-	}
-	mssqlConfig.URL().RawQuery = query.Encode()
-	return mssqlConfig.URL().String(), nil
+	params := map[string]string{}
+	if cfg.DSN != "" {
+		p, err := msdsn.Parse(cfg.DSN)
+		if err != nil {
+			return "", fmt.Errorf("解析连接字符串失败: %w", err)
+		}
+		for k, v := range p.Parameters {
+			params[k] = v
+		}
+	}
+
+	set := func(key, value string) {
+		if value != "" {
+			params[key] = value
+		}
+	}
+	set(msdsn.Server, cfg.Server)
+	if cfg.Port > 0 {
+		set(msdsn.Port, strconv.FormatUint(cfg.Port, 10))
+	}
+	set(msdsn.UserID, cfg.User)
+	set(msdsn.Password, cfg.Password)
+	set(msdsn.Database, cfg.DBName)
+	if cfg.Timeout > 0 {
+		set(msdsn.ConnectionTimeout, strconv.FormatUint(cfg.Timeout, 10))
+	}
+	if cfg.Encrypt != "" {
+		switch strings.ToLower(cfg.Encrypt) {
+		case "off":
+			// 不指定 encrypt 时驱动仅加密登录包且信任服务器证书，与 encrypt=false 不同
+			delete(params, msdsn.Encrypt)
+		case "required":
+			params[msdsn.Encrypt] = "true"
+		case "strict":
+			params[msdsn.Encrypt] = "strict"
+		default:
+			params[msdsn.Encrypt] = "disable"
+		}
+	}
+
+	for k, v := range cfg.Params {
+		params[k] = v
+	}
+
+	connStr := joinParams(params)
+	if _, err := msdsn.Parse(connStr); err != nil {
+		return "", fmt.Errorf("无效的连接参数: %w", err)
+	}
+	return connStr, nil
 }
 
 // setConnPool 配置连接池参数
-func setConnPool(db *sql.DB) {
-	db.SetMaxOpenConns(10)                 // 最大打开连接数
-	db.SetMaxIdleConns(5)                  // 最大空闲连接数
-	db.SetConnMaxLifetime(5 * time.Minute) // 连接最大存活时间
+// 最大打开连接数、存活时间和空闲时间为 0 时不限制，最大空闲连接数为 0 时不保留空闲连接
+func setConnPool(db *sql.DB, cfg config.DBConfig) {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)       // 最大打开连接数
+	db.SetMaxIdleConns(cfg.MaxIdleConns)       // 最大空闲连接数
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime) // 连接最大存活时间
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime) // 连接最大空闲时间
 }
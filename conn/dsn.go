@@ -0,0 +1,97 @@
+// conn/dsn.go
+package conn
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+
+	"github.com/mssql_ie/config"
+)
+
+// ParseDSN 解析 sqlserver:// 或 ADO 格式的连接字符串
+// 返回的配置包含连接字符串本身，以及从中解析出的服务器、端口、用户名、密码和数据库
+func ParseDSN(dsn string) (config.DBConfig, error) {
+	p, err := msdsn.Parse(dsn)
+	if err != nil {
+		return config.DBConfig{}, fmt.Errorf("解析连接字符串失败: %w", err)
+	}
+	params := p.Parameters
+	cfg := config.DBConfig{
+		DSN:      dsn,
+		Server:   params[msdsn.Server],
+		User:     params[msdsn.UserID],
+		Password: params[msdsn.Password],
+		DBName:   params[msdsn.Database],
+	}
+	if port := params[msdsn.Port]; port != "" {
+		if cfg.Port, err = strconv.ParseUint(port, 10, 16); err != nil {
+			return cfg, fmt.Errorf("连接字符串中的端口 %s 无效", port)
+		}
+	}
+	return cfg, nil
+}
+
+// 与驱动一致的 ADO 连接字符串键名同义词
+var paramSynonyms = map[string]string{
+	"application name":          msdsn.AppName,
+	"data source":               msdsn.Server,
+	"address":                   msdsn.Server,
+	"network address":           msdsn.Server,
+	"addr":                      msdsn.Server,
+	"user":                      msdsn.UserID,
+	"uid":                       msdsn.UserID,
+	"pwd":                       msdsn.Password,
+	"initial catalog":           msdsn.Database,
+	"column encryption setting": "columnencryption",
+}
+
+// ParseParams 解析 key=value 格式的连接参数，键名与 ADO 连接字符串相同（如 app name、packet size）
+// 键名不区分大小写，参数值在建立连接时由驱动校验
+func ParseParams(specs []string) (map[string]string, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	params := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		key, value, ok := strings.Cut(spec, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || key == "" {
+			return nil, fmt.Errorf("无效的连接参数 %s，应为 key=value 格式", spec)
+		}
+		if synonym, ok := paramSynonyms[key]; ok {
+			key = synonym
+		}
+		params[key] = strings.TrimSpace(value)
+	}
+	return params, nil
+}
+
+// joinParams 将连接参数拼接为 ADO 格式的连接字符串，键按字母顺序排列
+func joinParams(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(quoteValue(params[k]))
+		sb.WriteByte(';')
+	}
+	return sb.String()
+}
+
+// quoteValue 值包含分号、双引号或首尾空白时用双引号包裹，内部的双引号加倍
+func quoteValue(v string) string {
+	if !strings.ContainsAny(v, `;"`) && strings.TrimSpace(v) == v {
+		return v
+	}
+	return `"` + strings.ReplaceAll(v, `"`, `""`) + `"`
+}
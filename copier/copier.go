@@ -0,0 +1,419 @@
+// Package copier 在两个数据库之间直接复制数据，不经过中间文件
+package copier
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+
+	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/schema"
+	"github.com/mssql_ie/utils"
+)
+
+// Copy 将源库中表或查询的结果复制到目标库的表中
+// 数据以驱动返回的原生类型写入目标表，不经过字符串转换
+func Copy(src, dst *sql.DB, cfg config.CopyConfig) error {
+	if err := validateCopyConfig(cfg); err != nil {
+		return fmt.Errorf("配置校验失败: %w", err)
+	}
+
+	ctx := context.Background()
+	targetName := cfg.TargetTable
+	if targetName == "" {
+		targetName = cfg.Table
+	}
+	targetSchema, targetTable, err := utils.SplitQualifiedName(targetName)
+	if err != nil {
+		return fmt.Errorf("无效的目标表名: %w", err)
+	}
+
+	// 构建源查询
+	query := cfg.SQL
+	if cfg.Table != "" {
+		escaped, err := utils.EscapeQualifiedName(cfg.Table)
+		if err != nil {
+			return fmt.Errorf("无效的表名格式: %w", err)
+		}
+		query = fmt.Sprintf("SELECT * FROM %s", escaped)
+	}
+
+	// 指定表结构文档时先读取，文档有误时在修改目标库之前报错
+	var def *schema.Table
+	if cfg.CreateTable && cfg.SchemaFile != "" {
+		if def, err = documentTable(cfg); err != nil {
+			return err
+		}
+	}
+
+	// 使用专用连接，保证 IDENTITY_INSERT 等会话设置对插入生效
+	conn, err := dst.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("获取目标库连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	if cfg.CreateTable {
+		if err := createTargetTable(ctx, src, conn, def, cfg.Table, targetSchema, targetTable); err != nil {
+			return err
+		}
+	}
+
+	target, err := schema.LoadTable(ctx, conn, targetSchema, targetTable)
+	if err != nil {
+		return fmt.Errorf("获取目标表结构失败: %w", err)
+	}
+
+	if cfg.Truncate {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("TRUNCATE TABLE %s", target.QuotedName())); err != nil {
+			return fmt.Errorf("清空目标表失败: %w", err)
+		}
+	}
+
+	// 执行源查询
+	rows, err := src.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("执行源查询失败: %w", err)
+	}
+	defer rows.Close()
+
+	plan, err := buildCopyPlan(rows, target)
+	if err != nil {
+		return err
+	}
+
+	var total int
+	if cfg.Bulk {
+		total, err = bulkCopy(ctx, conn, rows, plan, target)
+	} else {
+		total, err = batchCopy(ctx, conn, rows, plan, target, cfg)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ 复制完成，共写入 %d 行数据到 %s\n", total, target.QuotedName())
+	return nil
+}
+
+// validateCopyConfig 校验复制配置
+func validateCopyConfig(cfg config.CopyConfig) error {
+	if (cfg.Table == "") == (cfg.SQL == "") {
+		return fmt.Errorf("必须且只能指定源表或源查询之一")
+	}
+	if cfg.Table == "" && cfg.TargetTable == "" {
+		return fmt.Errorf("使用源查询时必须指定目标表")
+	}
+	if cfg.CreateTable && cfg.Table == "" && cfg.SchemaFile == "" {
+		return fmt.Errorf("复制源查询时只能按表结构文档自动创建目标表")
+	}
+	if cfg.SchemaFile != "" && !cfg.CreateTable {
+		return fmt.Errorf("表结构文档只用于自动创建目标表，需同时开启自动建表")
+	}
+	if cfg.Batch <= 0 {
+		return fmt.Errorf("批量大小必须大于0（建议500-2000）")
+	}
+	if cfg.Bulk && cfg.SkipErrors {
+		return fmt.Errorf("批量复制模式不支持跳过错误行")
+	}
+	return nil
+}
+
+// documentTable 从表结构文档中查找源表（复制源查询时为目标表）的定义
+func documentTable(cfg config.CopyConfig) (*schema.Table, error) {
+	doc, err := schema.ReadDocument(cfg.SchemaFile)
+	if err != nil {
+		return nil, err
+	}
+	name := cfg.Table
+	if name == "" {
+		name = cfg.TargetTable
+	}
+	schemaName, tableName, err := utils.SplitQualifiedName(name)
+	if err != nil {
+		return nil, fmt.Errorf("无效的表名格式: %w", err)
+	}
+	def := doc.Table(schemaName, tableName)
+	if def == nil {
+		return nil, fmt.Errorf("表结构文档 %s 中不存在表 %s.%s", cfg.SchemaFile, schemaName, tableName)
+	}
+	return def, nil
+}
+
+// createTargetTable 按表定义在目标库创建表（目标表已存在时跳过），def 为 nil 时读取源表结构
+// 外键不会被创建，因为被引用的表在目标库中可能不存在
+func createTargetTable(ctx context.Context, src *sql.DB, conn *sql.Conn, def *schema.Table, sourceName, targetSchema, targetTable string) error {
+	var exists bool
+	quoted := utils.EscapeIdentifier(targetSchema) + "." + utils.EscapeIdentifier(targetTable)
+	err := conn.QueryRowContext(ctx, "SELECT CASE WHEN OBJECT_ID(?, 'U') IS NULL THEN 0 ELSE 1 END", quoted).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("检查目标表是否存在失败: %w", err)
+	}
+	if exists {
+		fmt.Printf("目标表 %s 已存在，跳过建表\n", quoted)
+		return nil
+	}
+
+	if def == nil {
+		sourceSchema, sourceTable, err := utils.SplitQualifiedName(sourceName)
+		if err != nil {
+			return fmt.Errorf("无效的表名格式: %w", err)
+		}
+		if def, err = schema.LoadTable(ctx, src, sourceSchema, sourceTable); err != nil {
+			return fmt.Errorf("获取源表结构失败: %w", err)
+		}
+	}
+	def.Schema, def.Name = targetSchema, targetTable
+	def.ForeignKeys = nil
+
+	for _, stmt := range append([]string{def.CreateSQL()}, def.IndexSQL()...) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("创建目标表失败: %w", err)
+		}
+	}
+	fmt.Printf("已创建目标表 %s\n", quoted)
+	return nil
+}
+
+// copyPlan 源列与目标列的对应关系
+type copyPlan struct {
+	Columns  []string // 目标列名（未转义）
+	Fields   []int    // 目标列在源结果集中的位置
+	DBTypes  []string // 源列的数据库类型名
+	Width    int      // 源结果集的列数
+	Identity bool     // 是否写入自增列
+}
+
+// buildCopyPlan 按列名将源结果集与目标表的可插入列对应起来
+func buildCopyPlan(rows *sql.Rows, target *schema.Table) (*copyPlan, error) {
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("获取源列类型失败: %w", err)
+	}
+
+	plan := &copyPlan{Width: len(colTypes)}
+	for i, ct := range colTypes {
+		found := false
+		for _, col := range target.Columns {
+			if !strings.EqualFold(col.Name, ct.Name()) {
+				continue
+			}
+			found = true
+			if !col.Insertable() {
+				break
+			}
+			plan.Columns = append(plan.Columns, col.Name)
+			plan.Fields = append(plan.Fields, i)
+			plan.DBTypes = append(plan.DBTypes, ct.DatabaseTypeName())
+			if col.Identity != nil {
+				plan.Identity = true
+			}
+			break
+		}
+		if !found {
+			return nil, fmt.Errorf("源列 %s 在目标表 %s 中不存在", ct.Name(), target.QuotedName())
+		}
+	}
+	if len(plan.Columns) == 0 {
+		return nil, fmt.Errorf("没有可以写入目标表的列")
+	}
+	return plan, nil
+}
+
+// scanRow 读取一行源数据并按目标列顺序返回参数
+func scanRow(rows *sql.Rows, plan *copyPlan, values, ptrs []interface{}) ([]interface{}, error) {
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(plan.Fields))
+	for i, field := range plan.Fields {
+		args[i] = normalizeValue(values[field], plan.DBTypes[i])
+	}
+	return args, nil
+}
+
+// normalizeValue 将驱动以 []byte 返回的定点数转换为字符串，避免被当作二进制写入
+func normalizeValue(v interface{}, dbType string) interface{} {
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+	switch dbType {
+	case "DECIMAL", "NUMERIC", "MONEY", "SMALLMONEY":
+		return string(b)
+	}
+	return b
+}
+
+// newScanBuffers 创建行数据接收容器
+func newScanBuffers(width int) ([]interface{}, []interface{}) {
+	values := make([]interface{}, width)
+	ptrs := make([]interface{}, width)
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	return values, ptrs
+}
+
+// batchCopy 使用参数化 INSERT 分批写入，每 cfg.Batch 行提交一次
+func batchCopy(ctx context.Context, conn *sql.Conn, rows *sql.Rows, plan *copyPlan, target *schema.Table, cfg config.CopyConfig) (int, error) {
+	if plan.Identity {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET IDENTITY_INSERT %s ON", target.QuotedName())); err != nil {
+			return 0, fmt.Errorf("开启 IDENTITY_INSERT 失败: %w", err)
+		}
+		defer conn.ExecContext(ctx, fmt.Sprintf("SET IDENTITY_INSERT %s OFF", target.QuotedName()))
+	}
+
+	safeCols := make([]string, len(plan.Columns))
+	placeholders := make([]string, len(plan.Columns))
+	for i, col := range plan.Columns {
+		safeCols[i] = utils.EscapeIdentifier(col)
+		placeholders[i] = "?"
+	}
+	insertSQL := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		target.QuotedName(), strings.Join(safeCols, ","), strings.Join(placeholders, ","))
+
+	values, ptrs := newScanBuffers(plan.Width)
+	var tx *sql.Tx
+	var stmt *sql.Stmt
+	batchCount, totalCount, rowNum := 0, 0, 0
+	errorRows := []int{}
+
+	begin := func() error {
+		var err error
+		if tx, err = conn.BeginTx(ctx, nil); err != nil {
+			return fmt.Errorf("开启事务失败: %w", err)
+		}
+		if stmt, err = tx.Prepare(insertSQL); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("预处理插入语句失败: %w", err)
+		}
+		return nil
+	}
+	if err := begin(); err != nil {
+		return 0, err
+	}
+
+	for rows.Next() {
+		rowNum++
+		args, err := scanRow(rows, plan, values, ptrs)
+		if err != nil {
+			tx.Rollback()
+			return totalCount, fmt.Errorf("读取源数据失败(行%d): %w", rowNum, err)
+		}
+
+		if _, err := stmt.Exec(args...); err != nil {
+			if cfg.SkipErrors {
+				errorRows = append(errorRows, rowNum)
+				continue
+			}
+			tx.Rollback()
+			return totalCount, fmt.Errorf("插入行失败(行%d): %w", rowNum, err)
+		}
+		batchCount++
+		totalCount++
+
+		// 达到批量大小提交事务
+		if batchCount >= cfg.Batch {
+			stmt.Close()
+			if err := tx.Commit(); err != nil {
+				return totalCount, fmt.Errorf("提交批量事务失败(累计%d行): %w", totalCount, err)
+			}
+			if err := begin(); err != nil {
+				return totalCount, err
+			}
+			batchCount = 0
+			fmt.Printf("已复制 %d 行...\n", totalCount)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return totalCount, fmt.Errorf("遍历源数据异常: %w", err)
+	}
+
+	// 提交剩余数据
+	stmt.Close()
+	if err := tx.Commit(); err != nil {
+		return totalCount, fmt.Errorf("提交剩余数据失败: %w", err)
+	}
+
+	if len(errorRows) > 0 {
+		fmt.Printf("⚠️  跳过 %d 行错误数据: %v\n", len(errorRows), errorRows)
+	}
+	return totalCount, nil
+}
+
+// bulkCopy 使用 SQL Server 批量复制协议（BULK INSERT）写入，整个复制在一个事务中完成
+func bulkCopy(ctx context.Context, conn *sql.Conn, rows *sql.Rows, plan *copyPlan, target *schema.Table) (int, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 批量复制只有指定 KEEP_IDENTITY 选项才保留自增值，驱动的 BulkOptions 不支持该选项，
+	// 自增列由目标表重新生成
+	if plan.Identity {
+		plan = withoutIdentity(plan, target)
+		fmt.Println("⚠️  批量复制模式下自增列的值将由目标表重新生成，引用这些值的外键可能失效；需要保留原值时请去掉 --bulk")
+	}
+
+	opts := mssql.BulkOptions{KeepNulls: true, Tablock: true}
+	stmt, err := tx.PrepareContext(ctx, mssql.CopyIn(target.QuotedName(), opts, plan.Columns...))
+	if err != nil {
+		return 0, fmt.Errorf("准备批量复制失败: %w", err)
+	}
+	defer stmt.Close()
+
+	values, ptrs := newScanBuffers(plan.Width)
+	totalCount := 0
+	for rows.Next() {
+		args, err := scanRow(rows, plan, values, ptrs)
+		if err != nil {
+			return totalCount, fmt.Errorf("读取源数据失败(行%d): %w", totalCount+1, err)
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return totalCount, fmt.Errorf("批量复制失败(行%d): %w", totalCount+1, err)
+		}
+		totalCount++
+		if totalCount%10000 == 0 {
+			fmt.Printf("已复制 %d 行...\n", totalCount)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return totalCount, fmt.Errorf("遍历源数据异常: %w", err)
+	}
+
+	// 不带参数执行一次以完成批量复制
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return totalCount, fmt.Errorf("完成批量复制失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return totalCount, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return totalCount, nil
+}
+
+// withoutIdentity 返回去掉自增列后的复制计划
+func withoutIdentity(plan *copyPlan, target *schema.Table) *copyPlan {
+	identity := make(map[string]bool)
+	for _, col := range target.Columns {
+		if col.Identity != nil {
+			identity[strings.ToLower(col.Name)] = true
+		}
+	}
+
+	p := &copyPlan{Width: plan.Width}
+	for i, col := range plan.Columns {
+		if identity[strings.ToLower(col)] {
+			continue
+		}
+		p.Columns = append(p.Columns, col)
+		p.Fields = append(p.Fields, plan.Fields[i])
+		p.DBTypes = append(p.DBTypes, plan.DBTypes[i])
+	}
+	return p
+}
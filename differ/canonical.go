@@ -0,0 +1,116 @@
+// differ/canonical.go
+package differ
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mssql_ie/schema"
+	"github.com/mssql_ie/utils"
+)
+
+// selectList 两侧读取键列和比较列时使用的表达式（表别名为 t），以列名为别名
+// 两侧列类型相同时直接读取列；类型不同时（如 varchar 与 nvarchar、datetime 与 datetime2）
+// 转换为相同的类型，使分块编号、行哈希和逐行比较的值不受列类型影响
+type selectList struct {
+	names []string
+	exprs []string
+}
+
+// newSelectList 按基准表和比较目标的列定义生成读取表达式
+func newSelectList(base, other *schema.Table, names []string) (selectList, error) {
+	l := selectList{names: names, exprs: make([]string, len(names))}
+	for i, name := range names {
+		a, ok := findColumn(base, name)
+		if !ok {
+			return l, fmt.Errorf("列 %s 在表 %s 中不存在", name, base.QuotedName())
+		}
+		b, ok := findColumn(other, name)
+		if !ok {
+			return l, fmt.Errorf("列 %s 在比较目标 %s 中不存在", name, other.QuotedName())
+		}
+		l.exprs[i] = canonicalExpr("t."+utils.EscapeIdentifier(name), a, b)
+	}
+	return l, nil
+}
+
+// aliased 返回带列名别名的表达式列表
+func (l selectList) aliased() string {
+	parts := make([]string, len(l.exprs))
+	for i, e := range l.exprs {
+		parts[i] = e + " AS " + utils.EscapeIdentifier(l.names[i])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// findColumn 按列名（不区分大小写）查找列定义
+func findColumn(t *schema.Table, name string) (schema.Column, bool) {
+	for _, c := range t.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c, true
+		}
+	}
+	return schema.Column{}, false
+}
+
+// canonicalExpr 返回两侧共用的列表达式
+func canonicalExpr(ref string, a, b schema.Column) string {
+	fa, fb := typeFamily(a.Type), typeFamily(b.Type)
+	if strings.EqualFold(a.Type, b.Type) && (fa == "string" || fa == "binary" || (a.Precision == b.Precision && a.Scale == b.Scale)) {
+		return ref
+	}
+	if (fa == "exact" && fb == "approx") || (fa == "approx" && fb == "exact") {
+		return fmt.Sprintf("CAST(%s AS float)", ref)
+	}
+	if fa != fb {
+		return fmt.Sprintf("RTRIM(CONVERT(nvarchar(max), %s))", ref)
+	}
+
+	switch fa {
+	case "string":
+		// 比较字符串时尾随空格不影响相等性（char 与 varchar）
+		return fmt.Sprintf("RTRIM(CAST(%s AS nvarchar(max)))", ref)
+	case "binary":
+		return fmt.Sprintf("CAST(%s AS varbinary(max))", ref)
+	case "datetime":
+		// 转换为两侧中较低的小数秒精度，如 datetime 与 datetime2 按毫秒比较
+		p := min(timePrecision(a), timePrecision(b))
+		if strings.EqualFold(a.Type, "datetimeoffset") && strings.EqualFold(b.Type, "datetimeoffset") {
+			return fmt.Sprintf("CAST(%s AS datetimeoffset(%d))", ref, p)
+		}
+		return fmt.Sprintf("CAST(%s AS datetime2(%d))", ref, p)
+	case "exact":
+		return fmt.Sprintf("CAST(%s AS decimal(38, %d))", ref, max(a.Scale, b.Scale))
+	case "approx":
+		return fmt.Sprintf("CAST(%s AS float)", ref)
+	}
+	return ref
+}
+
+// typeFamily 返回类型所属的类别，同类别的类型可以相互转换后比较
+func typeFamily(t string) string {
+	switch strings.ToLower(t) {
+	case "char", "varchar", "nchar", "nvarchar", "text", "ntext", "sysname":
+		return "string"
+	case "binary", "varbinary", "image":
+		return "binary"
+	case "date", "time", "datetime", "datetime2", "smalldatetime", "datetimeoffset":
+		return "datetime"
+	case "bit", "tinyint", "smallint", "int", "bigint", "decimal", "numeric", "money", "smallmoney":
+		return "exact"
+	case "float", "real":
+		return "approx"
+	}
+	return strings.ToLower(t)
+}
+
+// timePrecision 返回时间类型的小数秒位数
+func timePrecision(c schema.Column) int {
+	switch strings.ToLower(c.Type) {
+	case "datetime":
+		return 3
+	case "date", "smalldatetime":
+		return 0
+	}
+	return c.Scale
+}
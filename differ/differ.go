@@ -0,0 +1,412 @@
+// Package differ 按主键比较表与CSV文件（或两张表）之间的数据差异
+package differ
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/exporter"
+	"github.com/mssql_ie/importer"
+	"github.com/mssql_ie/schema"
+	"github.com/mssql_ie/utils"
+)
+
+// stagingTable 比较CSV文件时用于暂存文件数据的临时表
+const stagingTable = "#mssql_ie_diff"
+
+// side 参与比较的一方
+type side struct {
+	conn  *sql.Conn
+	table string // 转义后的表名
+}
+
+// hashParts 分块哈希由行哈希前 16 字节按 4 字节分段求和得到
+const hashParts = 4
+
+// maxFetchRows 逐行比较时每侧一次读入内存的大致行数上限
+const maxFetchRows = 100000
+
+// comparison 参与比较的键列和比较列及其读取表达式
+type comparison struct {
+	keys selectList
+	cols selectList
+}
+
+// bucket 分块汇总信息
+type bucket struct {
+	Count int64
+	Hash  [hashParts]int64
+}
+
+// Diff 比较基准表与CSV文件（cfg.CSVPath）或另一张表（cfg.TargetTable，可位于 other 库）
+// 先按主键哈希分块比较行数和行 SHA-256 哈希之和，只对不一致的分块逐行比较
+func Diff(base, other *sql.DB, cfg config.DiffConfig) error {
+	if err := validateDiffConfig(cfg); err != nil {
+		return fmt.Errorf("配置校验失败: %w", err)
+	}
+
+	ctx := context.Background()
+	baseConn, err := base.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	defer baseConn.Close()
+
+	schemaName, tableName, err := utils.SplitQualifiedName(cfg.Table)
+	if err != nil {
+		return fmt.Errorf("无效的表名格式: %w", err)
+	}
+	def, err := schema.LoadTable(ctx, baseConn, schemaName, tableName)
+	if err != nil {
+		return fmt.Errorf("获取表结构失败: %w", err)
+	}
+
+	keys, err := keyColumns(def, cfg.Keys)
+	if err != nil {
+		return err
+	}
+	cols := compareColumns(def, keys, cfg.CSVPath != "")
+
+	baseSide := side{conn: baseConn, table: def.QuotedName()}
+	var otherSide side
+	otherDef := def
+	if cfg.CSVPath != "" {
+		// 临时表与基准表结构相同
+		if err := stageCSV(ctx, baseConn, def, cols, cfg); err != nil {
+			return err
+		}
+		otherSide = side{conn: baseConn, table: stagingTable}
+	} else {
+		otherConn, err := other.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("获取比较目标连接失败: %w", err)
+		}
+		defer otherConn.Close()
+		targetSchema, targetTable, err := utils.SplitQualifiedName(cfg.TargetTable)
+		if err != nil {
+			return fmt.Errorf("无效的目标表名: %w", err)
+		}
+		if otherDef, err = schema.LoadTable(ctx, otherConn, targetSchema, targetTable); err != nil {
+			return fmt.Errorf("获取目标表结构失败: %w", err)
+		}
+		otherSide = side{conn: otherConn, table: otherDef.QuotedName()}
+	}
+
+	var cmp comparison
+	if cmp.keys, err = newSelectList(def, otherDef, keys); err != nil {
+		return err
+	}
+	if cmp.cols, err = newSelectList(def, otherDef, cols); err != nil {
+		return err
+	}
+
+	// 根据行数确定分块数量
+	n, err := bucketCount(ctx, baseSide, otherSide, cfg.ChunkSize)
+	if err != nil {
+		return err
+	}
+
+	baseBuckets, err := bucketSums(ctx, baseSide, cmp, n)
+	if err != nil {
+		return err
+	}
+	otherBuckets, err := bucketSums(ctx, otherSide, cmp, n)
+	if err != nil {
+		return err
+	}
+
+	var changedBuckets []int
+	for b := 0; b < n; b++ {
+		if baseBuckets[b] != otherBuckets[b] {
+			changedBuckets = append(changedBuckets, b)
+		}
+	}
+	fmt.Printf("共 %d 个分块，%d 个分块存在差异\n", n, len(changedBuckets))
+
+	// 每次读取的分块数使每侧读入内存的行数不超过 maxFetchRows
+	groupSize := max(1, maxFetchRows/cfg.ChunkSize)
+	result := &Result{Keys: keys, Columns: cols}
+	for start := 0; start < len(changedBuckets); start += groupSize {
+		end := min(start+groupSize, len(changedBuckets))
+		group := changedBuckets[start:end]
+		baseRows, err := fetchRows(ctx, baseSide, cmp, n, group, cfg.BinaryFormat)
+		if err != nil {
+			return err
+		}
+		otherRows, err := fetchRows(ctx, otherSide, cmp, n, group, cfg.BinaryFormat)
+		if err != nil {
+			return err
+		}
+		diffKeys := result.compare(baseRows, otherRows)
+		warnEmptyBuckets(group, diffKeys, baseRows, otherRows)
+	}
+
+	if err := writeResult(result, cfg); err != nil {
+		return err
+	}
+
+	fmt.Printf("比较完成: 新增 %d 行，删除 %d 行，修改 %d 行\n", result.Added, result.Removed, result.Changed)
+	return nil
+}
+
+// validateDiffConfig 校验比较配置
+func validateDiffConfig(cfg config.DiffConfig) error {
+	if cfg.Table == "" {
+		return fmt.Errorf("表名不能为空")
+	}
+	if (cfg.CSVPath == "") == (cfg.TargetTable == "") {
+		return fmt.Errorf("必须且只能指定CSV文件或目标表之一")
+	}
+	if cfg.ChunkSize <= 0 {
+		return fmt.Errorf("分块大小必须大于0")
+	}
+	return nil
+}
+
+// keyColumns 返回用于比较的键列，未指定时使用主键
+func keyColumns(def *schema.Table, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		if def.PrimaryKey == nil {
+			return nil, fmt.Errorf("表 %s 没有主键，请通过 --key 指定键列", def.QuotedName())
+		}
+		for _, c := range def.PrimaryKey.Columns {
+			keys = append(keys, c.Name)
+		}
+		return keys, nil
+	}
+
+	resolved := make([]string, len(keys))
+	for i, k := range keys {
+		found := false
+		for _, c := range def.Columns {
+			if strings.EqualFold(c.Name, k) {
+				resolved[i] = c.Name
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("键列 %s 在表 %s 中不存在", k, def.QuotedName())
+		}
+	}
+	return resolved, nil
+}
+
+// compareColumns 返回参与比较的非键列
+// 排除大对象和空间类型；与CSV比较时还排除计算列和 rowversion 列
+func compareColumns(def *schema.Table, keys []string, fromCSV bool) []string {
+	isKey := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		isKey[k] = true
+	}
+
+	var cols []string
+	for _, c := range def.Columns {
+		if isKey[c.Name] || (fromCSV && !c.Insertable()) {
+			continue
+		}
+		switch strings.ToLower(c.Type) {
+		case "text", "ntext", "image", "xml", "geometry", "geography", "sql_variant":
+			continue
+		}
+		cols = append(cols, c.Name)
+	}
+	return cols
+}
+
+// stageCSV 创建与表结构相同的临时表并将CSV文件导入其中
+func stageCSV(ctx context.Context, conn *sql.Conn, def *schema.Table, cols []string, cfg config.DiffConfig) error {
+	// UNION ALL 使 SELECT INTO 生成的临时表不带自增属性
+	var insertable []string
+	for _, c := range def.Columns {
+		if c.Insertable() {
+			insertable = append(insertable, utils.EscapeIdentifier(c.Name))
+		}
+	}
+	colList := strings.Join(insertable, ", ")
+	create := fmt.Sprintf("SELECT TOP 0 %s INTO %s FROM %s UNION ALL SELECT TOP 0 %s FROM %s",
+		colList, stagingTable, def.QuotedName(), colList, def.QuotedName())
+	if _, err := conn.ExecContext(ctx, create); err != nil {
+		return fmt.Errorf("创建临时表失败: %w", err)
+	}
+
+	importCfg := config.ImportConfig{
+		Table:        stagingTable,
+		CSVPath:      cfg.CSVPath,
+		Batch:        1000,
+		Header:       cfg.Header,
+		Delimiter:    cfg.Delimiter,
+		BinaryFormat: cfg.BinaryFormat,
+		FileCharset:  cfg.FileCharset,
+	}
+	if _, err := importer.LoadFileOnConn(ctx, conn, importCfg, def.QuotedName()); err != nil {
+		return fmt.Errorf("加载CSV文件失败: %w", err)
+	}
+	return nil
+}
+
+// bucketCount 根据两侧较大的行数和分块大小计算分块数量
+func bucketCount(ctx context.Context, a, b side, chunkSize int) (int, error) {
+	var maxRows int64
+	for _, s := range []side{a, b} {
+		var count int64
+		if err := s.conn.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT_BIG(*) FROM %s", s.table)).Scan(&count); err != nil {
+			return 0, fmt.Errorf("统计表 %s 行数失败: %w", s.table, err)
+		}
+		if count > maxRows {
+			maxRows = count
+		}
+	}
+	n := int((maxRows + int64(chunkSize) - 1) / int64(chunkSize))
+	if n < 1 {
+		n = 1
+	}
+	return n, nil
+}
+
+// bucketExpr 返回按键列计算分块编号的表达式
+// 键值与行哈希相同地序列化后取 SHA-256 的前 4 字节，同一键值在两侧总是落在同一分块
+func bucketExpr(keys selectList, n int) string {
+	return fmt.Sprintf("CAST(SUBSTRING(HASHBYTES('SHA2_256', (SELECT %s FOR XML RAW, BINARY BASE64)), 1, 4) AS BIGINT) %% %d",
+		keys.aliased(), n)
+}
+
+// bucketSums 按分块统计行数和哈希
+// 每行的键列和比较列以 FOR XML RAW 序列化（NULL 与空字符串可区分，时间和浮点数不丢失精度）后计算 SHA-256，
+// 分块哈希为行哈希前 16 字节按 4 字节分段的和：与行顺序无关，且重复行不会像 CHECKSUM_AGG 那样相互抵消
+func bucketSums(ctx context.Context, s side, cmp comparison, n int) (map[int]bucket, error) {
+	rowCols := cmp.keys.aliased()
+	if len(cmp.cols.names) > 0 {
+		rowCols += ", " + cmp.cols.aliased()
+	}
+	hashSums := make([]string, hashParts)
+	for i := range hashSums {
+		hashSums[i] = fmt.Sprintf("SUM(CAST(CAST(SUBSTRING(h, %d, 4) AS INT) AS BIGINT))", i*4+1)
+	}
+	query := fmt.Sprintf(`
+		/* mssql_ie tool query for diff buckets*/
+		SELECT b, COUNT_BIG(*), %s
+		FROM (
+			SELECT %s AS b, HASHBYTES('SHA2_256', (SELECT %s FOR XML RAW, BINARY BASE64)) AS h
+			FROM %s AS t
+		) AS r
+		GROUP BY b`, strings.Join(hashSums, ", "), bucketExpr(cmp.keys, n), rowCols, s.table)
+
+	rows, err := s.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("计算表 %s 的分块校验和失败: %w", s.table, err)
+	}
+	defer rows.Close()
+
+	sums := make(map[int]bucket, n)
+	for rows.Next() {
+		var b int
+		var v bucket
+		dest := []interface{}{&b, &v.Count}
+		for i := range v.Hash {
+			dest = append(dest, &v.Hash[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("解析分块校验和失败: %w", err)
+		}
+		sums[b] = v
+	}
+	return sums, rows.Err()
+}
+
+// fetchedRow 逐行比较时读取的一行
+type fetchedRow struct {
+	Bucket int       // 所在分块
+	Values []*string // 键列和比较列的值，NULL 为 nil
+}
+
+// nullKey 键值拼接时 NULL 的表示，与任何格式化后的值都不同
+const nullKey = "\x00"
+
+// fetchRows 读取指定分块中的所有行，按键值索引
+// 键值重复时无法按键对应两侧的行，返回错误
+func fetchRows(ctx context.Context, s side, cmp comparison, n int, buckets []int, binaryFormat string) (map[string]fetchedRow, error) {
+	ids := make([]string, len(buckets))
+	for i, b := range buckets {
+		ids[i] = fmt.Sprintf("%d", b)
+	}
+	selects := cmp.keys.aliased()
+	if len(cmp.cols.names) > 0 {
+		selects += ", " + cmp.cols.aliased()
+	}
+	bucketOf := bucketExpr(cmp.keys, n)
+	query := fmt.Sprintf("SELECT %s, %s FROM %s AS t WHERE %s IN (%s)",
+		selects, bucketOf, s.table, bucketOf, strings.Join(ids, ","))
+
+	rows, err := s.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("读取表 %s 的差异分块失败: %w", s.table, err)
+	}
+	defer rows.Close()
+
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("获取列类型失败: %w", err)
+	}
+	width := len(colTypes) - 1 // 最后一列为分块编号
+	values := make([]interface{}, width)
+	ptrs := make([]interface{}, len(colTypes))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+
+	result := make(map[string]fetchedRow)
+	for rows.Next() {
+		var r fetchedRow
+		ptrs[width] = &r.Bucket
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("解析行数据失败: %w", err)
+		}
+		r.Values = make([]*string, width)
+		keyParts := make([]string, len(cmp.keys.names))
+		for i, v := range values {
+			if v != nil {
+				text := exporter.FormatValue(v, colTypes[i].DatabaseTypeName(), binaryFormat)
+				r.Values[i] = &text
+			}
+			if i < len(keyParts) {
+				keyParts[i] = nullKey
+				if r.Values[i] != nil {
+					keyParts[i] = *r.Values[i]
+				}
+			}
+		}
+		key := strings.Join(keyParts, "\x1f")
+		if _, ok := result[key]; ok {
+			return nil, fmt.Errorf("表 %s 中键值 (%s) 重复，无法按键比较，请通过 --key 指定唯一的键列",
+				s.table, strings.Join(displayValues(r.Values[:len(keyParts)]), ", "))
+		}
+		result[key] = r
+	}
+	return result, rows.Err()
+}
+
+// warnEmptyBuckets 汇总不一致但逐行比较没有发现差异的分块输出警告
+// 通常说明两侧的值在格式化后无法区分（如浮点数或时间的精度超出输出格式），差异不会出现在结果中
+func warnEmptyBuckets(buckets []int, diffKeys []string, base, other map[string]fetchedRow) {
+	found := make(map[int]bool, len(diffKeys))
+	for _, k := range diffKeys {
+		if r, ok := base[k]; ok {
+			found[r.Bucket] = true
+		} else {
+			found[other[k].Bucket] = true
+		}
+	}
+	var missing []int
+	for _, b := range buckets {
+		if !found[b] {
+			missing = append(missing, b)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Printf("⚠️  %d 个分块的校验和不一致，但逐行比较未发现差异（可能是值格式化后无法区分）: %v\n", len(missing), missing)
+	}
+}
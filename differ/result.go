@@ -0,0 +1,198 @@
+// differ/result.go
+package differ
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mssql_ie/config"
+)
+
+// 差异类型
+const (
+	StatusAdded   = "added"   // 仅存在于比较目标中
+	StatusRemoved = "removed" // 仅存在于基准表中
+	StatusChanged = "changed" // 两侧都存在但值不同
+)
+
+// nullText CSV 输出中 NULL 值的表示（JSON 输出为 null）
+const nullText = "NULL"
+
+// RowDiff 单行差异，NULL 值为 nil
+type RowDiff struct {
+	Status  string             `json:"status"`
+	Key     map[string]*string `json:"key"`
+	Changed []string           `json:"changed,omitempty"`
+	Base    map[string]*string `json:"base,omitempty"`
+	Other   map[string]*string `json:"other,omitempty"`
+}
+
+// Result 比较结果
+type Result struct {
+	Keys    []string
+	Columns []string
+	Rows    []RowDiff
+	Added   int
+	Removed int
+	Changed int
+}
+
+// compare 逐行比较同一批分块中两侧的数据，返回存在差异的键值
+func (r *Result) compare(base, other map[string]fetchedRow) []string {
+	keys := make([]string, 0, len(base)+len(other))
+	for k := range base {
+		keys = append(keys, k)
+	}
+	for k := range other {
+		if _, ok := base[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var diffKeys []string
+	for _, k := range keys {
+		b, inBase := base[k]
+		o, inOther := other[k]
+		switch {
+		case !inOther:
+			r.Removed++
+			r.Rows = append(r.Rows, RowDiff{Status: StatusRemoved, Key: r.keyMap(b.Values), Base: r.valueMap(b.Values)})
+			diffKeys = append(diffKeys, k)
+		case !inBase:
+			r.Added++
+			r.Rows = append(r.Rows, RowDiff{Status: StatusAdded, Key: r.keyMap(o.Values), Other: r.valueMap(o.Values)})
+			diffKeys = append(diffKeys, k)
+		default:
+			var changed []string
+			for i, col := range r.Columns {
+				if !equalValues(b.Values[len(r.Keys)+i], o.Values[len(r.Keys)+i]) {
+					changed = append(changed, col)
+				}
+			}
+			if len(changed) > 0 {
+				r.Changed++
+				diffKeys = append(diffKeys, k)
+				r.Rows = append(r.Rows, RowDiff{
+					Status:  StatusChanged,
+					Key:     r.keyMap(b.Values),
+					Changed: changed,
+					Base:    r.valueMap(b.Values),
+					Other:   r.valueMap(o.Values),
+				})
+			}
+		}
+	}
+	return diffKeys
+}
+
+// equalValues 比较两个可能为 NULL 的值，NULL 与空字符串不相等
+func equalValues(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// displayValue 返回值在CSV输出和错误信息中的文本，NULL 显示为 nullText
+func displayValue(v *string) string {
+	if v == nil {
+		return nullText
+	}
+	return *v
+}
+
+// displayValues 返回多个值的显示文本
+func displayValues(values []*string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = displayValue(v)
+	}
+	return out
+}
+
+// keyMap 提取键列的值
+func (r *Result) keyMap(row []*string) map[string]*string {
+	m := make(map[string]*string, len(r.Keys))
+	for i, k := range r.Keys {
+		m[k] = row[i]
+	}
+	return m
+}
+
+// valueMap 提取比较列的值
+func (r *Result) valueMap(row []*string) map[string]*string {
+	m := make(map[string]*string, len(r.Columns))
+	for i, c := range r.Columns {
+		m[c] = row[len(r.Keys)+i]
+	}
+	return m
+}
+
+// writeResult 按配置的格式输出比较结果
+func writeResult(r *Result, cfg config.DiffConfig) error {
+	var out io.Writer = os.Stdout
+	if cfg.Output != "" {
+		file, err := os.Create(cfg.Output)
+		if err != nil {
+			return fmt.Errorf("创建差异输出文件失败: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if cfg.Format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if r.Rows == nil {
+			r.Rows = []RowDiff{}
+		}
+		if err := enc.Encode(r.Rows); err != nil {
+			return fmt.Errorf("输出差异失败: %w", err)
+		}
+		return nil
+	}
+	return writeCSV(out, r)
+}
+
+// writeCSV 以CSV格式输出差异
+// 每行包含差异类型、修改的列以及各列的值；修改的列以 "基准值 => 目标值" 形式突出显示，NULL 显示为 NULL
+func writeCSV(out io.Writer, r *Result) error {
+	writer := csv.NewWriter(out)
+	header := append([]string{"_status", "_changed"}, r.Keys...)
+	header = append(header, r.Columns...)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("输出差异失败: %w", err)
+	}
+
+	for _, d := range r.Rows {
+		record := []string{d.Status, strings.Join(d.Changed, "|")}
+		for _, k := range r.Keys {
+			record = append(record, displayValue(d.Key[k]))
+		}
+		changed := make(map[string]bool, len(d.Changed))
+		for _, c := range d.Changed {
+			changed[c] = true
+		}
+		for _, c := range r.Columns {
+			switch {
+			case d.Status == StatusAdded:
+				record = append(record, displayValue(d.Other[c]))
+			case changed[c]:
+				record = append(record, displayValue(d.Base[c])+" => "+displayValue(d.Other[c]))
+			default:
+				record = append(record, displayValue(d.Base[c]))
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("输出差异失败: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,58 @@
+// differ/result_test.go
+package differ
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// str 返回字符串指针
+func str(s string) *string { return &s }
+
+func TestCompareNullAndEmpty(t *testing.T) {
+	r := &Result{Keys: []string{"id"}, Columns: []string{"name"}}
+	base := map[string]fetchedRow{
+		"1": {Bucket: 0, Values: []*string{str("1"), nil}},
+		"2": {Bucket: 0, Values: []*string{str("2"), nil}},
+	}
+	other := map[string]fetchedRow{
+		"1": {Bucket: 0, Values: []*string{str("1"), str("")}},
+		"2": {Bucket: 0, Values: []*string{str("2"), nil}},
+	}
+
+	diffKeys := r.compare(base, other)
+	if r.Changed != 1 || len(diffKeys) != 1 || diffKeys[0] != "1" {
+		t.Fatalf("修改行数 = %d，差异键 = %v，期望只有键 1 被修改", r.Changed, diffKeys)
+	}
+
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, r); err != nil {
+		t.Fatalf("输出差异失败: %v", err)
+	}
+	want := "_status,_changed,id,name\nchanged,name,1,NULL => \n"
+	if got := buf.String(); got != want {
+		t.Errorf("CSV 输出 = %q，期望 %q", got, want)
+	}
+}
+
+func TestCompareAddedAndRemoved(t *testing.T) {
+	r := &Result{Keys: []string{"id"}, Columns: []string{"name"}}
+	base := map[string]fetchedRow{"1": {Values: []*string{str("1"), str("a")}}}
+	other := map[string]fetchedRow{"2": {Values: []*string{str("2"), nil}}}
+
+	diffKeys := r.compare(base, other)
+	if r.Removed != 1 || r.Added != 1 || len(diffKeys) != 2 {
+		t.Fatalf("删除 %d 行，新增 %d 行，差异键 %v，期望各 1 行", r.Removed, r.Added, diffKeys)
+	}
+	if v := r.Rows[1].Other["name"]; v != nil {
+		t.Errorf("新增行的 name = %q，期望 NULL", *v)
+	}
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, r); err != nil {
+		t.Fatalf("输出差异失败: %v", err)
+	}
+	if !strings.Contains(buf.String(), "added,,2,NULL\n") {
+		t.Errorf("CSV 输出 %q 中 NULL 未显示为 NULL", buf.String())
+	}
+}
@@ -0,0 +1,115 @@
+// Package dump 整库转储与恢复（表结构 + 数据）
+package dump
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/exporter"
+	"github.com/mssql_ie/schema"
+)
+
+// dumpBinaryFormat 转储时二进制数据统一使用 base64，保证可以无损恢复
+const dumpBinaryFormat = "base64"
+
+// Dump 导出匹配的所有表的表结构与数据，并生成清单文件
+func Dump(db *sql.DB, cfg config.DumpConfig) error {
+	if cfg.OutDir == "" {
+		return fmt.Errorf("输出目录不能为空")
+	}
+	if err := os.MkdirAll(cfg.OutDir, 0755); err != nil {
+		return fmt.Errorf("创建输出目录失败: %w", err)
+	}
+
+	ctx := context.Background()
+	include := cfg.Tables
+	if len(include) == 0 {
+		include = []string{"*.*"}
+	}
+	tables, err := exporter.ResolveTables(ctx, db, include, cfg.ExcludeTables)
+	if err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return fmt.Errorf("没有需要转储的表")
+	}
+	fmt.Printf("共 %d 张表需要转储\n", len(tables))
+
+	// 读取表结构
+	defs := make([]*schema.Table, len(tables))
+	for i, t := range tables {
+		if defs[i], err = schema.LoadTable(ctx, db, t.Schema, t.Name); err != nil {
+			return err
+		}
+	}
+
+	// 导出数据
+	exportCfg := config.ExportConfig{
+		Header:       true,
+		Delimiter:    ',',
+		BinaryFormat: dumpBinaryFormat,
+		FileCharset:  "utf8",
+		OutDir:       cfg.OutDir,
+		Retry:        cfg.Retry,
+		Isolation:    cfg.Isolation,
+	}
+	results := exporter.ExportTables(db, tables, exportCfg, exporter.Concurrency(db, 0))
+	if err := exporter.PrintTableSummary(results); err != nil {
+		return err
+	}
+
+	// 写入建表脚本和清单
+	m := &Manifest{
+		Version:      1,
+		CreatedAt:    time.Now(),
+		Server:       cfg.Server,
+		Database:     cfg.Database,
+		BinaryFormat: dumpBinaryFormat,
+	}
+	for i, def := range defs {
+		m.Tables = append(m.Tables, ManifestTable{
+			File:      filepath.Base(results[i].Path),
+			Rows:      results[i].Rows,
+			DependsOn: dependsOn(def),
+			Schema:    def,
+		})
+	}
+	if err := writeSchemaScript(cfg.OutDir, schema.NewDocument(cfg.Database, defs)); err != nil {
+		return err
+	}
+	return writeManifest(cfg.OutDir, m)
+}
+
+// writeSchemaScript 写入建表脚本
+func writeSchemaScript(dir string, doc *schema.Document) error {
+	file, err := os.Create(filepath.Join(dir, SchemaFile))
+	if err != nil {
+		return fmt.Errorf("创建建表脚本失败: %w", err)
+	}
+	defer file.Close()
+
+	if err := doc.WriteSQL(file); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// dependsOn 返回表通过外键引用的其他表
+func dependsOn(t *schema.Table) []string {
+	self := t.Schema + "." + t.Name
+	seen := make(map[string]bool)
+	var deps []string
+	for _, fk := range t.ForeignKeys {
+		name := fk.ReferencedSchema + "." + fk.ReferencedTable
+		if name != self && !seen[name] {
+			seen[name] = true
+			deps = append(deps, name)
+		}
+	}
+	return deps
+}
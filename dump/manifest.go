@@ -0,0 +1,113 @@
+// dump/manifest.go
+package dump
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mssql_ie/schema"
+)
+
+// ManifestFile 转储目录中的清单文件名
+const ManifestFile = "manifest.json"
+
+// SchemaFile 转储目录中的建表脚本文件名（仅供查看，恢复时使用清单中的定义）
+const SchemaFile = "schema.sql"
+
+// Manifest 转储清单
+type Manifest struct {
+	Version      int             `json:"version"`
+	CreatedAt    time.Time       `json:"created_at"`
+	Server       string          `json:"server"`
+	Database     string          `json:"database"`
+	BinaryFormat string          `json:"binary_format"`
+	Tables       []ManifestTable `json:"tables"`
+}
+
+// ManifestTable 清单中的单张表
+type ManifestTable struct {
+	File      string        `json:"file"`
+	Rows      int           `json:"rows"`
+	DependsOn []string      `json:"depends_on,omitempty"` // 外键引用的其他表（schema.table）
+	Schema    *schema.Table `json:"definition"`
+}
+
+// Name 返回 schema.table 形式的表名
+func (t ManifestTable) Name() string {
+	return t.Schema.Schema + "." + t.Schema.Name
+}
+
+// writeManifest 写入清单文件
+func writeManifest(dir string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化清单失败: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), data, 0644); err != nil {
+		return fmt.Errorf("写入清单文件失败: %w", err)
+	}
+	return nil
+}
+
+// ReadManifest 读取转储目录中的清单文件
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("读取清单文件失败: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("解析清单文件失败: %w", err)
+	}
+	for i, t := range m.Tables {
+		if t.Schema == nil {
+			return nil, fmt.Errorf("清单中第 %d 张表缺少表结构定义", i+1)
+		}
+	}
+	return &m, nil
+}
+
+// dependencyOrder 按外键依赖关系排序，被引用的表排在前面
+// 存在循环引用时，剩余的表按清单顺序追加（恢复时外键约束已禁用，不影响导入）
+func dependencyOrder(tables []ManifestTable) []ManifestTable {
+	index := make(map[string]int, len(tables))
+	for i, t := range tables {
+		index[t.Name()] = i
+	}
+
+	done := make([]bool, len(tables))
+	ordered := make([]ManifestTable, 0, len(tables))
+	for len(ordered) < len(tables) {
+		progressed := false
+		for i, t := range tables {
+			if done[i] {
+				continue
+			}
+			ready := true
+			for _, dep := range t.DependsOn {
+				if j, ok := index[dep]; ok && j != i && !done[j] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[i] = true
+				ordered = append(ordered, t)
+				progressed = true
+			}
+		}
+		if !progressed {
+			// 循环引用：按清单顺序追加剩余的表
+			for i, t := range tables {
+				if !done[i] {
+					done[i] = true
+					ordered = append(ordered, t)
+				}
+			}
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,128 @@
+// dump/restore.go
+package dump
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+
+	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/importer"
+)
+
+// Restore 根据转储目录重建表结构并按外键依赖顺序导入数据
+// 导入期间禁用所有外键和检查约束，导入完成后重新启用并校验
+func Restore(db *sql.DB, cfg config.RestoreConfig) error {
+	m, err := ReadManifest(cfg.InDir)
+	if err != nil {
+		return err
+	}
+	tables := dependencyOrder(m.Tables)
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	// 建表：已存在的表保留原结构，只导入数据
+	var created []ManifestTable
+	for _, t := range tables {
+		exists, err := tableExists(ctx, conn, t.Schema.QuotedName())
+		if err != nil {
+			return err
+		}
+		if exists {
+			fmt.Printf("⚠️  表 %s 已存在，跳过建表\n", t.Name())
+			continue
+		}
+		if err := execAll(ctx, conn, append([]string{t.Schema.CreateSQL()}, t.Schema.IndexSQL()...)); err != nil {
+			return fmt.Errorf("创建表 %s 失败: %w", t.Name(), err)
+		}
+		created = append(created, t)
+		fmt.Printf("已创建表 %s\n", t.Name())
+	}
+
+	// 所有表创建完成后再创建外键，避免引用的表尚不存在
+	for _, t := range created {
+		if err := execAll(ctx, conn, t.Schema.ForeignKeySQL()); err != nil {
+			return fmt.Errorf("创建表 %s 的外键失败: %w", t.Name(), err)
+		}
+	}
+
+	// 禁用约束，导入结束后无论成功与否都重新启用
+	for _, t := range tables {
+		stmt := fmt.Sprintf("ALTER TABLE %s NOCHECK CONSTRAINT ALL", t.Schema.QuotedName())
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("禁用表 %s 的约束失败: %w", t.Name(), err)
+		}
+	}
+
+	loadErr := loadTables(db, cfg, m, tables)
+
+	var checkErr error
+	for _, t := range tables {
+		stmt := fmt.Sprintf("ALTER TABLE %s WITH CHECK CHECK CONSTRAINT ALL", t.Schema.QuotedName())
+		if _, err := conn.ExecContext(ctx, stmt); err != nil && checkErr == nil {
+			checkErr = fmt.Errorf("重新启用表 %s 的约束失败: %w", t.Name(), err)
+		}
+	}
+
+	if loadErr != nil {
+		return loadErr
+	}
+	return checkErr
+}
+
+// loadTables 按顺序导入各表数据
+func loadTables(db *sql.DB, cfg config.RestoreConfig, m *Manifest, tables []ManifestTable) error {
+	for i, t := range tables {
+		fmt.Printf("[%d/%d] 正在导入表 %s\n", i+1, len(tables), t.Name())
+
+		importCfg := config.ImportConfig{
+			Table:        t.Schema.QuotedName(),
+			CSVPath:      filepath.Join(cfg.InDir, t.File),
+			Batch:        cfg.Batch,
+			Header:       true,
+			Delimiter:    ',',
+			SkipErrors:   cfg.SkipErrors,
+			BinaryFormat: m.BinaryFormat,
+			FileCharset:  "utf8",
+			Retry:        cfg.Retry,
+		}
+		// 自增列需要在导入连接上开启 IDENTITY_INSERT
+		if t.Schema.HasIdentity() {
+			importCfg.Hooks = config.HookConfig{
+				PreSQL:        []string{fmt.Sprintf("SET IDENTITY_INSERT %s ON", t.Schema.QuotedName())},
+				PostSQL:       []string{fmt.Sprintf("SET IDENTITY_INSERT %s OFF", t.Schema.QuotedName())},
+				PostSQLAlways: true,
+			}
+		}
+		if err := importer.CSVToTable(db, importCfg); err != nil {
+			return fmt.Errorf("导入表 %s 失败: %w", t.Name(), err)
+		}
+	}
+	return nil
+}
+
+// tableExists 判断表是否存在
+func tableExists(ctx context.Context, conn *sql.Conn, quotedName string) (bool, error) {
+	var exists bool
+	err := conn.QueryRowContext(ctx, "SELECT CASE WHEN OBJECT_ID(?, 'U') IS NULL THEN 0 ELSE 1 END", quotedName).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("检查表 %s 是否存在失败: %w", quotedName, err)
+	}
+	return exists, nil
+}
+
+// execAll 依次执行多条语句
+func execAll(ctx context.Context, conn *sql.Conn, stmts []string) error {
+	for _, stmt := range stmts {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+// exporter/columns.go
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/sqlparams"
+	"github.com/mssql_ie/utils"
+)
+
+// tableColumns 按定义顺序查询表（或视图）的列名
+func tableColumns(ctx context.Context, q querier, escapedTable string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `
+		/* mssql_ie tool query for export columns*/
+		SELECT name FROM sys.columns WHERE object_id = OBJECT_ID(?) ORDER BY column_id`, escapedTable)
+	if err != nil {
+		return nil, fmt.Errorf("查询表列失败: %w", err)
+	}
+	defer rows.Close()
+
+	var cols []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("读取表列失败: %w", err)
+		}
+		cols = append(cols, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取表列失败: %w", err)
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("表 %s 不存在或没有可访问的列", escapedTable)
+	}
+	return cols, nil
+}
+
+// columnIndex 按名称（不区分大小写，可带方括号）查找表列，返回表中的实际列名
+type columnIndex map[string]string
+
+func newColumnIndex(cols []string) columnIndex {
+	idx := make(columnIndex, len(cols))
+	for _, c := range cols {
+		idx[strings.ToLower(c)] = c
+	}
+	return idx
+}
+
+func (idx columnIndex) lookup(name string) (string, error) {
+	key := strings.TrimSpace(name)
+	if len(key) >= 2 && key[0] == '[' && key[len(key)-1] == ']' {
+		key = strings.ReplaceAll(key[1:len(key)-1], "]]", "]")
+	}
+	col, ok := idx[strings.ToLower(key)]
+	if !ok {
+		return "", fmt.Errorf("列 %s 不存在", name)
+	}
+	return col, nil
+}
+
+// selectList 根据 --columns / --exclude-columns 构建转义后的列列表
+func selectList(cfg config.ExportConfig, cols []string, idx columnIndex) (string, error) {
+	selected := cols
+	if len(cfg.Columns) > 0 {
+		selected = nil
+		seen := make(map[string]bool)
+		for _, name := range cfg.Columns {
+			col, err := idx.lookup(name)
+			if err != nil {
+				return "", err
+			}
+			if seen[col] {
+				return "", fmt.Errorf("列 %s 重复指定", name)
+			}
+			seen[col] = true
+			selected = append(selected, col)
+		}
+	}
+	if len(cfg.ExcludeColumns) > 0 {
+		excluded := make(map[string]bool)
+		for _, name := range cfg.ExcludeColumns {
+			col, err := idx.lookup(name)
+			if err != nil {
+				return "", err
+			}
+			excluded[col] = true
+		}
+		var kept []string
+		for _, col := range selected {
+			if !excluded[col] {
+				kept = append(kept, col)
+			}
+		}
+		selected = kept
+	}
+	if len(selected) == 0 {
+		return "", fmt.Errorf("排除后没有需要导出的列")
+	}
+
+	escaped := make([]string, len(selected))
+	for i, col := range selected {
+		escaped[i] = utils.EscapeIdentifier(col)
+	}
+	return strings.Join(escaped, ", "), nil
+}
+
+// orderByClause 解析 --order-by（如 "created_at desc, id"），校验列名并转义
+func orderByClause(orderBy string, idx columnIndex) (string, error) {
+	var items []string
+	for _, item := range SplitPatterns([]string{orderBy}) {
+		name, dir := item, ""
+		if i := strings.LastIndexAny(item, " \t"); i > 0 {
+			switch d := strings.ToUpper(strings.TrimSpace(item[i+1:])); d {
+			case "ASC", "DESC":
+				name, dir = strings.TrimSpace(item[:i]), " "+d
+			}
+		}
+		col, err := idx.lookup(name)
+		if err != nil {
+			return "", fmt.Errorf("无效的排序列: %w", err)
+		}
+		items = append(items, utils.EscapeIdentifier(col)+dir)
+	}
+	if len(items) == 0 {
+		return "", fmt.Errorf("排序列不能为空")
+	}
+	return strings.Join(items, ", "), nil
+}
+
+// predicateKeywords --where 中可以使用的关键字
+var predicateKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IS": true, "NULL": true,
+	"IN": true, "LIKE": true, "BETWEEN": true, "ESCAPE": true,
+}
+
+// predicateOperators --where 中可以使用的运算符，较长的运算符在前
+var predicateOperators = []string{"<=", ">=", "<>", "!=", "!<", "!>", "=", "<", ">", "+", "-", "*", "/", "%"}
+
+// wherePredicate 校验 --where 条件并返回可以放入 WHERE (...) 的谓词
+// 条件只能由表的列（按实际列名转义）、关键字 AND/OR/NOT/IS/NULL/IN/LIKE/BETWEEN/ESCAPE、
+// 字符串和数值常量、比较和算术运算符、括号以及 --param 定义的 @参数 组成；
+// 函数调用、子查询、限定名、注释和分号都会被拒绝，需要计算的值请通过 --param 传入
+func wherePredicate(where string, idx columnIndex, params []sqlparams.Param) (string, error) {
+	defined := make(map[string]bool, len(params))
+	for _, p := range params {
+		defined[strings.ToLower(p.Name)] = true
+	}
+
+	var out []string
+	depth := 0
+	for i := 0; i < len(where); {
+		c := where[i]
+		rest := where[i:]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case strings.HasPrefix(rest, "--") || strings.HasPrefix(rest, "/*"):
+			return "", fmt.Errorf("过滤条件不能包含注释")
+		case c == '(':
+			depth++
+			out = append(out, "(")
+			i++
+		case c == ')':
+			if depth--; depth < 0 {
+				return "", fmt.Errorf("过滤条件中的右括号没有对应的左括号")
+			}
+			out = append(out, ")")
+			i++
+		case c == ',':
+			out = append(out, ",")
+			i++
+		case c == '\'' || ((c == 'N' || c == 'n') && strings.HasPrefix(rest[1:], "'")):
+			// 字符串常量，两个单引号表示一个单引号
+			j := strings.IndexByte(rest, '\'') + 1
+			for {
+				k := strings.IndexByte(rest[j:], '\'')
+				if k < 0 {
+					return "", fmt.Errorf("过滤条件中的字符串未闭合")
+				}
+				j += k + 1
+				if !strings.HasPrefix(rest[j:], "'") {
+					break
+				}
+				j++
+			}
+			out = append(out, rest[:j])
+			i += j
+		case c >= '0' && c <= '9' || c == '.' && len(rest) > 1 && rest[1] >= '0' && rest[1] <= '9':
+			m := numberPattern.FindString(rest)
+			out = append(out, m)
+			i += len(m)
+		case c == '@':
+			m := wordPattern.FindString(rest[1:])
+			if m == "" || !defined[strings.ToLower(m)] {
+				return "", fmt.Errorf("过滤条件中的参数 @%s 未通过 --param 定义", m)
+			}
+			out = append(out, "@"+m)
+			i += len(m) + 1
+		case c == '[':
+			j := 1
+			for {
+				k := strings.IndexByte(rest[j:], ']')
+				if k < 0 {
+					return "", fmt.Errorf("过滤条件中的方括号未闭合")
+				}
+				j += k + 1
+				if !strings.HasPrefix(rest[j:], "]") {
+					break
+				}
+				j++
+			}
+			col, err := predicateColumn(rest[:j], rest[j:], idx)
+			if err != nil {
+				return "", err
+			}
+			out = append(out, col)
+			i += j
+		case wordPattern.MatchString(rest[:1]):
+			m := wordPattern.FindString(rest)
+			i += len(m)
+			if kw := strings.ToUpper(m); predicateKeywords[kw] {
+				out = append(out, kw)
+				continue
+			}
+			col, err := predicateColumn(m, where[i:], idx)
+			if err != nil {
+				return "", err
+			}
+			out = append(out, col)
+		default:
+			op := ""
+			for _, o := range predicateOperators {
+				if strings.HasPrefix(rest, o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return "", fmt.Errorf("过滤条件中不能使用字符 %q", rest[:1])
+			}
+			out = append(out, op)
+			i += len(op)
+		}
+	}
+	if depth != 0 {
+		return "", fmt.Errorf("过滤条件中的括号未闭合")
+	}
+	if len(out) == 0 {
+		return "", fmt.Errorf("过滤条件不能为空")
+	}
+	return strings.Join(out, " "), nil
+}
+
+var (
+	wordPattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*`)
+	numberPattern = regexp.MustCompile(`^(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?`)
+)
+
+// predicateColumn 按表的实际列校验过滤条件中的名称 name 并转义，next 为名称之后的文本
+func predicateColumn(name, next string, idx columnIndex) (string, error) {
+	next = strings.TrimLeft(next, " \t\r\n")
+	if strings.HasPrefix(next, "(") {
+		return "", fmt.Errorf("过滤条件中不能调用函数 %s，需要计算的值请通过 --param 传入", name)
+	}
+	if strings.HasPrefix(next, ".") {
+		return "", fmt.Errorf("过滤条件中不能使用限定名 %s.，只能引用导出表的列", name)
+	}
+	col, err := idx.lookup(name)
+	if err != nil {
+		return "", fmt.Errorf("无效的过滤条件: %w", err)
+	}
+	return utils.EscapeIdentifier(col), nil
+}
@@ -0,0 +1,97 @@
+// exporter/columns_test.go
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/sqlparams"
+)
+
+func TestWherePredicate(t *testing.T) {
+	idx := newColumnIndex([]string{"id", "status", "amount", "Order Date"})
+	params := []sqlparams.Param{{Name: "Min", Type: "int", Decl: "INT", Value: int64(100)}}
+
+	tests := []struct {
+		name    string
+		where   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "列、字符串和数值常量",
+			where: "status = 'paid' and amount > 0",
+			want:  "[status] = 'paid' AND [amount] > 0",
+		},
+		{
+			name:  "方括号列名和参数",
+			where: "[order date] >= @min OR ID IN (1, 2.5, .5e3)",
+			want:  "[Order Date] >= @min OR [id] IN ( 1 , 2.5 , .5e3 )",
+		},
+		{
+			name:  "字符串中的引号、注释和分号",
+			where: "status <> N'it''s -- ; /* x'",
+			want:  "[status] <> N'it''s -- ; /* x'",
+		},
+		{
+			name:  "IS NOT NULL 和 BETWEEN",
+			where: "(status IS NOT NULL) AND amount BETWEEN 1 AND 10",
+			want:  "( [status] IS NOT NULL ) AND [amount] BETWEEN 1 AND 10",
+		},
+		{name: "不存在的列", where: "state = 'paid'", wantErr: "列 state 不存在"},
+		{name: "始终为真的条件引用子查询", where: "1=1 OR EXISTS(SELECT 1 FROM sys.objects)", wantErr: "不能调用函数 EXISTS"},
+		{name: "函数调用", where: "amount > ABS (id)", wantErr: "不能调用函数 ABS"},
+		{name: "限定名", where: "dbo.t.id = 1", wantErr: "不能使用限定名 dbo."},
+		{name: "未定义的参数", where: "amount > @max", wantErr: "参数 @max 未通过 --param 定义"},
+		{name: "注释", where: "id = 1 -- AND status = 'x'", wantErr: "不能包含注释"},
+		{name: "块注释", where: "id = 1 /* x */", wantErr: "不能包含注释"},
+		{name: "分号", where: "id = 1; DROP TABLE t", wantErr: "不能使用字符 \";\""},
+		{name: "双引号标识符", where: `"id" = 1`, wantErr: "不能使用字符"},
+		{name: "提前闭合外层括号", where: "id = 1) OR (1 = 1", wantErr: "右括号没有对应的左括号"},
+		{name: "括号未闭合", where: "(id = 1", wantErr: "括号未闭合"},
+		{name: "字符串未闭合", where: "status = 'paid", wantErr: "字符串未闭合"},
+		{name: "方括号未闭合", where: "[status = 1", wantErr: "方括号未闭合"},
+		{name: "空条件", where: "  ", wantErr: "不能为空"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := wherePredicate(tt.where, idx, params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误 = %v，期望包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("意外错误: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("wherePredicate(%q) = %q，期望 %q", tt.where, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildTableQueryWhere(t *testing.T) {
+	cfg := config.ExportConfig{
+		Table:   "sales.Orders",
+		Where:   "status = @status",
+		OrderBy: "id desc",
+		Params:  []sqlparams.Param{{Name: "status", Type: "nvarchar", Decl: "NVARCHAR(MAX)", Value: "paid"}},
+	}
+	got, err := buildTableQuery(cfg, []string{"id", "status"})
+	if err != nil {
+		t.Fatalf("意外错误: %v", err)
+	}
+	want := "SELECT * FROM [sales].[Orders] WHERE ([status] = @status) ORDER BY [id] DESC"
+	if got != want {
+		t.Errorf("查询 = %q，期望 %q", got, want)
+	}
+
+	// 参数值通过 sp_executesql 绑定，不出现在查询文本中
+	bound, args := sqlparams.Bind(got, cfg.Params)
+	if strings.Contains(bound, "paid") || len(args) != 3 || args[0] != got {
+		t.Errorf("绑定后的查询 = %q，参数 = %v", bound, args)
+	}
+}
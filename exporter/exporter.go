@@ -2,16 +2,25 @@
 package exporter
 
 import (
+	"context"
 	"database/sql"
 	"encoding/base64"
 	"encoding/csv"
 	"fmt"
-	"os"
+	"io"
+	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
+	mssql "github.com/microsoft/go-mssqldb"
+
 	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/fileio"
+	"github.com/mssql_ie/hooks"
+	"github.com/mssql_ie/masking"
+	"github.com/mssql_ie/retry"
+	"github.com/mssql_ie/sqlparams"
+	"github.com/mssql_ie/transforms"
 	"github.com/mssql_ie/utils"
 )
 
@@ -24,23 +33,75 @@ func TableToCSV(db *sql.DB, cfg config.ExportConfig) error {
 		return fmt.Errorf("CSV文件路径不能为空")
 	}
 
+	// 指定了列选择、过滤或排序时按表的实际列校验
+	var cols []string
+	if len(cfg.Columns) > 0 || len(cfg.ExcludeColumns) > 0 || cfg.Where != "" || cfg.OrderBy != "" {
+		escapedTable, err := utils.EscapeQualifiedName(cfg.Table)
+		if err != nil {
+			return fmt.Errorf("无效的表名格式: %w", err)
+		}
+		if cols, err = tableColumns(context.Background(), db, escapedTable); err != nil {
+			return err
+		}
+	}
+
+	query, err := buildTableQuery(cfg, cols)
+	if err != nil {
+		return err
+	}
+
+	return exportQueryResultToCSV(db, query, cfg)
+}
+
+// buildTableQuery 构建表导出查询
+// cols 为表的实际列名，仅在指定了 --columns、--exclude-columns、--where 或 --order-by 时需要
+func buildTableQuery(cfg config.ExportConfig, cols []string) (string, error) {
 	// 安全地转义表名
 	escapedTable, err := utils.EscapeQualifiedName(cfg.Table)
 	if err != nil {
-		return fmt.Errorf("无效的表名格式: %w", err)
+		return "", fmt.Errorf("无效的表名格式: %w", err)
+	}
+
+	// 列列表
+	selectCols := "*"
+	idx := newColumnIndex(cols)
+	if len(cfg.Columns) > 0 || len(cfg.ExcludeColumns) > 0 {
+		if selectCols, err = selectList(cfg, cols, idx); err != nil {
+			return "", err
+		}
 	}
 
 	// 构建查询
 	var query string
 	if cfg.Limit > 0 {
-		query = fmt.Sprintf("SELECT * FROM %s", escapedTable)
 		// 添加TOP限制
-		query = fmt.Sprintf("SELECT TOP %d * FROM %s", cfg.Limit, escapedTable)
+		query = fmt.Sprintf("SELECT TOP %d %s FROM %s", cfg.Limit, selectCols, escapedTable)
 	} else {
-		query = fmt.Sprintf("SELECT * FROM %s", escapedTable)
+		query = fmt.Sprintf("SELECT %s FROM %s", selectCols, escapedTable)
 	}
 
-	return exportQueryResultToCSV(db, query, cfg)
+	// 显式要求时添加 WITH (NOLOCK) 提示（读取未提交数据，可能读到重复或缺失的行）
+	if cfg.NoLock {
+		query += " WITH (NOLOCK)"
+	}
+
+	if cfg.Where != "" {
+		where, err := wherePredicate(cfg.Where, idx, cfg.Params)
+		if err != nil {
+			return "", err
+		}
+		query += fmt.Sprintf(" WHERE (%s)", where)
+	}
+
+	if cfg.OrderBy != "" {
+		orderBy, err := orderByClause(cfg.OrderBy, idx)
+		if err != nil {
+			return "", err
+		}
+		query += " ORDER BY " + orderBy
+	}
+
+	return query, nil
 }
 
 // SQLToCSV 执行自定义SQL并将结果导出到CSV文件
@@ -55,31 +116,117 @@ func SQLToCSV(db *sql.DB, cfg config.ExportConfig) error {
 	return exportQueryResultToCSV(db, cfg.SQL, cfg)
 }
 
-// exportQueryResultToCSV 通用导出逻辑
+// exportQueryResultToCSV 通用导出逻辑，遇到暂时性错误时从头重写文件
+// 没有前后置SQL和脚本准备批次时在新连接上重新导出；否则这些批次只执行一次，
+// 仅在同一连接上重试服务器返回的暂时性错误，有准备批次时不重试（准备批次不一定可以重复执行）
 func exportQueryResultToCSV(db *sql.DB, query string, cfg config.ExportConfig) error {
-	// 添加WITH (NOLOCK) 提示以避免锁定
-	if cfg.Table != "" && !strings.Contains(strings.ToUpper(query), "WITH (NOLOCK)") {
-		query = strings.TrimSuffix(query, ";")
-		query += " WITH (NOLOCK)"
+	ctx := context.Background()
+	export := func(conn *sql.Conn) error {
+		return withIsolation(ctx, conn, cfg, func(q querier) error {
+			_, err := writeQueryResult(ctx, q, query, cfg)
+			return err
+		})
 	}
 
-	// 执行查询
-	rows, err := db.Query(query)
+	hookCfg := cfg.Hooks
+	hookCfg.PreSQL = append(append([]string{}, cfg.Hooks.PreSQL...), cfg.SetupSQL...)
+	if len(hookCfg.PreSQL) == 0 && len(hookCfg.PostSQL) == 0 {
+		return retry.Do(ctx, cfg.Retry, "导出", func() error {
+			conn, err := db.Conn(ctx)
+			if err != nil {
+				return fmt.Errorf("获取数据库连接失败: %w", err)
+			}
+			defer conn.Close()
+			return export(conn)
+		})
+	}
+
+	// 获取专用连接，保证前后置SQL与导出查询在同一会话中执行
+	conn, err := db.Conn(ctx)
 	if err != nil {
-		return fmt.Errorf("执行查询失败: %w", err)
+		return fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	return hooks.Wrap(ctx, conn, hookCfg, func() error {
+		if len(cfg.SetupSQL) > 0 {
+			return export(conn)
+		}
+		return retry.DoOnConn(ctx, cfg.Retry, "导出", func() error { return export(conn) })
+	})
+}
+
+// writeQueryResult 在指定连接或事务上执行查询并写入CSV文件，返回导出的行数
+func writeQueryResult(ctx context.Context, q querier, query string, cfg config.ExportConfig) (int, error) {
+	startedAt := time.Now()
+
+	// 执行查询，参数通过 sp_executesql 绑定而不是拼接到查询中
+	boundQuery, args := sqlparams.Bind(query, cfg.Params)
+	rows, err := q.QueryContext(ctx, boundQuery, args...)
+	if err != nil {
+		return 0, fmt.Errorf("执行查询失败: %w", err)
 	}
 	defer rows.Close()
 
-	// 获取列名
 	cols, err := rows.Columns()
 	if err != nil {
-		return fmt.Errorf("获取列名失败: %w", err)
+		return 0, fmt.Errorf("获取列名失败: %w", err)
+	}
+	colTypes, rowCount, err := writeResultSet(rows, cols, cfg)
+	if err != nil {
+		return rowCount, err
+	}
+
+	if cfg.Manifest {
+		m, err := buildManifest(query, cfg, colTypes, rowCount, startedAt)
+		if err != nil {
+			return rowCount, err
+		}
+		if err := fileio.WriteManifest(cfg.CSVPath, m); err != nil {
+			return rowCount, err
+		}
+	}
+
+	fmt.Printf("✅ 导出完成，共 %d 行数据，文件路径: %s\n", rowCount, cfg.CSVPath)
+	return rowCount, nil
+}
+
+// writeResultSet 将当前结果集写入 cfg.CSVPath，返回列类型和导出的行数
+// 不调用 rows.NextResultSet，由调用方决定是否继续读取后续结果集
+func writeResultSet(rows *sql.Rows, cols []string, cfg config.ExportConfig) ([]*sql.ColumnType, int, error) {
+	// 获取列类型，用于区分真正的二进制列与驱动以 []byte 返回的 decimal/GUID 等类型
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取列类型失败: %w", err)
+	}
+	dbTypes := make([]string, len(colTypes))
+	for i, ct := range colTypes {
+		dbTypes[i] = ct.DatabaseTypeName()
+	}
+
+	// 值转换和脱敏：多表导出时各表的列不同，配置的列已在导出前对所有表的列统一检查（见 checkTableColumnRules）；
+	// 存储过程的多个结果集由 writeProcResults 在导出后统一检查
+	chains := make([]*transforms.Chain, len(cols))
+	masks := make([]*masking.Rule, len(cols))
+	for i, col := range cols {
+		chains[i] = cfg.Transforms.For(col)
+		masks[i] = cfg.Masks.For(col)
+	}
+	if len(cfg.Tables) == 0 && cfg.Proc == "" {
+		if cfg.Transforms != nil {
+			if err := cfg.Transforms.Check(cols); err != nil {
+				return nil, 0, err
+			}
+		}
+		if err := cfg.Masks.Check(cols); err != nil {
+			return nil, 0, err
+		}
 	}
 
-	// 创建CSV文件
-	file, err := os.Create(cfg.CSVPath)
+	// 创建CSV文件（按需压缩和加密）
+	file, err := fileio.Create(cfg.CSVPath, fileio.CreateOptions{Passphrase: cfg.Passphrase})
 	if err != nil {
-		return fmt.Errorf("创建CSV文件失败: %w", err)
+		return nil, 0, fmt.Errorf("创建CSV文件失败: %w", err)
 	}
 	defer file.Close()
 
@@ -87,12 +234,11 @@ func exportQueryResultToCSV(db *sql.DB, query string, cfg config.ExportConfig) e
 	transformer := utils.GetTransformersWrite(file, cfg.FileCharset)
 	writer := csv.NewWriter(transformer)
 	writer.Comma = cfg.Delimiter
-	defer writer.Flush()
 
 	// 写入列标题
 	if cfg.Header {
 		if err := writer.Write(cols); err != nil {
-			return fmt.Errorf("写入列名失败: %w", err)
+			return nil, 0, fmt.Errorf("写入列名失败: %w", err)
 		}
 	}
 
@@ -107,17 +253,25 @@ func exportQueryResultToCSV(db *sql.DB, query string, cfg config.ExportConfig) e
 	rowCount := 0
 	for rows.Next() {
 		if err := rows.Scan(valuePtrs...); err != nil {
-			return fmt.Errorf("解析行数据失败(行%d): %w", rowCount+1, err)
+			return nil, rowCount, fmt.Errorf("解析行数据失败(行%d): %w", rowCount+1, err)
 		}
 
 		// 转换为字符串
 		row := make([]string, len(cols))
 		for i, v := range values {
-			row[i] = convertValueToString(v, cfg.BinaryFormat)
+			row[i] = convertValueToString(v, dbTypes[i], cfg.BinaryFormat)
+			if chains[i] != nil {
+				if row[i], err = chains[i].Apply(row[i]); err != nil {
+					return nil, rowCount, fmt.Errorf("转换值失败(行%d): %w", rowCount+1, err)
+				}
+			}
+			if masks[i] != nil {
+				row[i] = cfg.Masks.Apply(masks[i], row[i])
+			}
 		}
 
 		if err := writer.Write(row); err != nil {
-			return fmt.Errorf("写入CSV行失败(行%d): %w", rowCount+1, err)
+			return nil, rowCount, fmt.Errorf("写入CSV行失败(行%d): %w", rowCount+1, err)
 		}
 		rowCount++
 
@@ -133,21 +287,100 @@ func exportQueryResultToCSV(db *sql.DB, query string, cfg config.ExportConfig) e
 	}
 
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("遍历行数据异常: %w", err)
+		return nil, rowCount, fmt.Errorf("遍历行数据异常: %w", err)
 	}
 
-	fmt.Printf("✅ 导出完成，共 %d 行数据，文件路径: %s\n", rowCount, cfg.CSVPath)
-	return nil
+	// 依次刷新CSV、字符集转换、压缩和加密层，任何一层失败都意味着文件不完整
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, rowCount, fmt.Errorf("写入CSV文件失败: %w", err)
+	}
+	if closer, ok := transformer.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return nil, rowCount, fmt.Errorf("写入CSV文件失败: %w", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return nil, rowCount, fmt.Errorf("写入CSV文件失败: %w", err)
+	}
+
+	return colTypes, rowCount, nil
+}
+
+// buildManifest 生成导出文件的清单，记录查询、列、行数以及文件的大小和 SHA-256
+func buildManifest(query string, cfg config.ExportConfig, colTypes []*sql.ColumnType, rowCount int, startedAt time.Time) (*fileio.Manifest, error) {
+	size, sum, err := fileio.Checksum(cfg.CSVPath)
+	if err != nil {
+		return nil, fmt.Errorf("计算文件校验和失败: %w", err)
+	}
+
+	columns := make([]fileio.ManifestColumn, len(colTypes))
+	for i, ct := range colTypes {
+		columns[i] = fileio.ManifestColumn{Name: ct.Name(), Type: columnTypeSQL(ct)}
+	}
+
+	m := &fileio.Manifest{
+		Version:    fileio.ManifestVersion,
+		File:       filepath.Base(cfg.CSVPath),
+		Query:      query,
+		Server:     cfg.Server,
+		Database:   cfg.Database,
+		Columns:    columns,
+		Rows:       rowCount,
+		Bytes:      size,
+		SHA256:     sum,
+		Header:     cfg.Header,
+		Delimiter:  string(cfg.Delimiter),
+		Charset:    cfg.FileCharset,
+		Compressed: fileio.IsCompressed(cfg.CSVPath),
+		Encrypted:  cfg.Passphrase != "",
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+	}
+	return m, nil
+}
+
+// columnTypeSQL 返回结果列的 SQL 类型描述，如 NVARCHAR(50)、DECIMAL(18,2)
+func columnTypeSQL(ct *sql.ColumnType) string {
+	name := ct.DatabaseTypeName()
+	if precision, scale, ok := ct.DecimalSize(); ok {
+		return fmt.Sprintf("%s(%d,%d)", name, precision, scale)
+	}
+	if length, ok := ct.Length(); ok {
+		switch {
+		case length <= 0 || length > 8000:
+			return name + "(MAX)"
+		default:
+			return fmt.Sprintf("%s(%d)", name, length)
+		}
+	}
+	return name
+}
+
+// FormatValue 按导出CSV的规则将数据库返回值转换为字符串，供其他命令复用
+func FormatValue(v interface{}, dbType string, binaryFormat string) string {
+	return convertValueToString(v, dbType, binaryFormat)
 }
 
 // convertValueToString 将数据库返回值转换为字符串
-func convertValueToString(v interface{}, binaryFormat string) string {
+// dbType 为列的数据库类型名（如 DECIMAL、UNIQUEIDENTIFIER），用于处理驱动以 []byte 返回的非二进制类型
+func convertValueToString(v interface{}, dbType string, binaryFormat string) string {
 	if v == nil {
 		return ""
 	}
 
 	switch val := v.(type) {
 	case []byte:
+		switch dbType {
+		case "DECIMAL", "NUMERIC", "MONEY", "SMALLMONEY":
+			// 驱动以文本形式返回定点数
+			return string(val)
+		case "UNIQUEIDENTIFIER":
+			var guid mssql.UniqueIdentifier
+			if err := guid.Scan(val); err == nil {
+				return guid.String()
+			}
+		}
 		return convertBinaryToString(val, binaryFormat)
 	case string:
 		return val
@@ -168,11 +401,26 @@ func convertValueToString(v interface{}, binaryFormat string) string {
 	case bool:
 		return strconv.FormatBool(val)
 	case time.Time:
-		return val.Format("2006-01-02 15:04:05.000")
+		return formatTime(val, dbType)
 	default:
 		return fmt.Sprintf("%v", val)
 	}
 }
+
+// formatTime 按列类型格式化时间，保留 datetime2/time 的 100 纳秒精度和 datetimeoffset 的时区偏移
+func formatTime(t time.Time, dbType string) string {
+	switch dbType {
+	case "DATETIME2":
+		return t.Format("2006-01-02 15:04:05.0000000")
+	case "TIME":
+		return t.Format("15:04:05.0000000")
+	case "DATETIMEOFFSET":
+		return t.Format("2006-01-02 15:04:05.0000000 -07:00")
+	default: // datetime、smalldatetime、date
+		return t.Format("2006-01-02 15:04:05.000")
+	}
+}
+
 func convertBinaryToString(b []byte, binaryFormat string) string {
 	if b == nil || len(b) == 0 {
 		return ""
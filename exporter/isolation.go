@@ -0,0 +1,83 @@
+// exporter/isolation.go
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mssql_ie/config"
+)
+
+// 支持的导出隔离级别
+var isolationLevels = map[string]sql.IsolationLevel{
+	"read-uncommitted": sql.LevelReadUncommitted,
+	"read-committed":   sql.LevelReadCommitted,
+	"repeatable-read":  sql.LevelRepeatableRead,
+	"snapshot":         sql.LevelSnapshot,
+}
+
+// IsolationNames 返回支持的隔离级别名称，用于参数说明和错误提示
+func IsolationNames() []string {
+	return []string{"read-uncommitted", "read-committed", "snapshot", "repeatable-read"}
+}
+
+// ParseIsolation 解析隔离级别名称，为空时返回 sql.LevelDefault（使用会话的隔离级别，不开启事务）
+func ParseIsolation(name string) (sql.IsolationLevel, error) {
+	if name == "" {
+		return sql.LevelDefault, nil
+	}
+	level, ok := isolationLevels[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("不支持的隔离级别 %s，可选值: %s", name, strings.Join(IsolationNames(), ", "))
+	}
+	return level, nil
+}
+
+// querier 抽象 *sql.Conn 与 *sql.Tx 共有的查询方法
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// withIsolation 按 cfg.Isolation 在事务中执行 fn；未指定隔离级别时直接在连接上执行
+func withIsolation(ctx context.Context, conn *sql.Conn, cfg config.ExportConfig, fn func(q querier) error) error {
+	level, err := ParseIsolation(cfg.Isolation)
+	if err != nil {
+		return err
+	}
+	if level == sql.LevelDefault {
+		return fn(conn)
+	}
+
+	if level == sql.LevelSnapshot {
+		if err := checkSnapshotAllowed(ctx, conn); err != nil {
+			return err
+		}
+	}
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{Isolation: level})
+	if err != nil {
+		return fmt.Errorf("开启 %s 事务失败: %w", cfg.Isolation, err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	// 只读事务，提交仅用于结束事务
+	return tx.Commit()
+}
+
+// checkSnapshotAllowed 检查当前数据库是否允许快照隔离
+func checkSnapshotAllowed(ctx context.Context, conn *sql.Conn) error {
+	var state int
+	err := conn.QueryRowContext(ctx, "SELECT snapshot_isolation_state FROM sys.databases WHERE database_id = DB_ID()").Scan(&state)
+	if err != nil {
+		return fmt.Errorf("查询快照隔离设置失败: %w", err)
+	}
+	// 1 = ON
+	if state != 1 {
+		return fmt.Errorf("当前数据库未启用快照隔离，请先执行 ALTER DATABASE ... SET ALLOW_SNAPSHOT_ISOLATION ON，或选择其他隔离级别")
+	}
+	return nil
+}
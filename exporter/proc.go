@@ -0,0 +1,245 @@
+// exporter/proc.go
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/fileio"
+	"github.com/mssql_ie/hooks"
+	"github.com/mssql_ie/sqlparams"
+	"github.com/mssql_ie/utils"
+)
+
+// procReturnColumn 调用批次最后一个结果集的首列名，用于将返回值和输出参数与存储过程的结果集区分开
+const procReturnColumn = "__mssqlie_return_value"
+
+// procResult 存储过程导出的单个结果集文件
+type procResult struct {
+	cfg      config.ExportConfig
+	colTypes []*sql.ColumnType
+	rows     int
+}
+
+// procOutputs 存储过程的返回值和输出参数
+type procOutputs struct {
+	returnValue *int64
+	names       []string // 输出参数按声明顺序排列
+	values      map[string]string
+}
+
+// ProcToCSV 执行存储过程，并将每个结果集写入单独的CSV文件（如 report_1.csv、report_2.csv）
+// 返回值和输出参数在读取完所有结果集后获取，启用清单时记录到每个文件的清单中
+func ProcToCSV(db *sql.DB, cfg config.ExportConfig) error {
+	if cfg.Proc == "" {
+		return fmt.Errorf("存储过程名不能为空")
+	}
+	if cfg.CSVPath == "" {
+		return fmt.Errorf("CSV文件路径不能为空")
+	}
+
+	batch, err := buildProcBatch(cfg)
+	if err != nil {
+		return err
+	}
+
+	// 存储过程可能有副作用（写入数据、发送通知等），失败时不重新执行
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	return hooks.Wrap(ctx, conn, cfg.Hooks, func() error {
+		return withIsolation(ctx, conn, cfg, func(q querier) error {
+			return writeProcResults(ctx, q, batch, cfg)
+		})
+	})
+}
+
+// buildProcBatch 生成调用存储过程的批次：声明返回值和输出参数变量，
+// 以同名参数传入 --param 指定的参数，最后以一个结果集返回返回值和输出参数
+func buildProcBatch(cfg config.ExportConfig) (string, error) {
+	proc, err := utils.EscapeQualifiedName(cfg.Proc)
+	if err != nil {
+		return "", fmt.Errorf("存储过程名无效: %w", err)
+	}
+
+	decls := []string{"@" + procReturnColumn + " INT"}
+	args := make([]string, 0, len(cfg.Params)+len(cfg.OutParams))
+	selects := []string{"@" + procReturnColumn + " AS " + utils.EscapeIdentifier(procReturnColumn)}
+	for _, p := range cfg.Params {
+		args = append(args, fmt.Sprintf("@%s = @%s", p.Name, p.Name))
+	}
+	for _, p := range cfg.OutParams {
+		decls = append(decls, fmt.Sprintf("@%s %s", p.Name, p.Decl))
+		args = append(args, fmt.Sprintf("@%s = @%s OUTPUT", p.Name, p.Name))
+		selects = append(selects, fmt.Sprintf("@%s AS %s", p.Name, utils.EscapeIdentifier(p.Name)))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "DECLARE %s;\n", strings.Join(decls, ", "))
+	fmt.Fprintf(&b, "EXEC @%s = %s", procReturnColumn, proc)
+	if len(args) > 0 {
+		b.WriteString(" " + strings.Join(args, ", "))
+	}
+	fmt.Fprintf(&b, ";\nSELECT %s;", strings.Join(selects, ", "))
+	return b.String(), nil
+}
+
+// writeProcResults 执行调用批次，依次将各结果集写入文件，最后写入清单
+func writeProcResults(ctx context.Context, q querier, batch string, cfg config.ExportConfig) error {
+	startedAt := time.Now()
+
+	query, args := sqlparams.Bind(batch, cfg.Params)
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("执行存储过程失败: %w", err)
+	}
+	defer rows.Close()
+
+	var results []procResult
+	var outputs *procOutputs
+	var allCols []string
+	for {
+		cols, err := rows.Columns()
+		if err != nil {
+			return fmt.Errorf("获取列名失败: %w", err)
+		}
+		switch {
+		case len(cols) > 0 && cols[0] == procReturnColumn:
+			if outputs, err = scanProcOutputs(rows, cols, cfg.BinaryFormat); err != nil {
+				return err
+			}
+		case len(cols) > 0:
+			setCfg := cfg
+			setCfg.CSVPath = ResultSetPath(cfg.CSVPath, len(results)+1)
+			colTypes, rowCount, err := writeResultSet(rows, cols, setCfg)
+			if err != nil {
+				return fmt.Errorf("导出第 %d 个结果集失败: %w", len(results)+1, err)
+			}
+			results = append(results, procResult{cfg: setCfg, colTypes: colTypes, rows: rowCount})
+			allCols = append(allCols, cols...)
+			fmt.Printf("✅ 第 %d 个结果集导出完成，共 %d 行数据，文件路径: %s\n", len(results), rowCount, setCfg.CSVPath)
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	// 存储过程在输出结果集之后抛出的错误在这里返回
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("执行存储过程失败: %w", err)
+	}
+	if outputs == nil {
+		return fmt.Errorf("未获取到存储过程 %s 的返回值", cfg.Proc)
+	}
+
+	// 结果集的列只有执行后才能得知：规则未匹配任何结果集的列时删除已写入的文件并报错，
+	// 避免敏感数据因列名拼写错误而以明文留在磁盘上
+	if err := checkProcColumnRules(allCols, cfg); err != nil {
+		for _, r := range results {
+			os.Remove(r.cfg.CSVPath)
+		}
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Printf("⚠️  存储过程 %s 没有返回结果集\n", cfg.Proc)
+	}
+	printProcOutputs(outputs)
+
+	if cfg.Manifest {
+		for i, r := range results {
+			m, err := buildManifest(batch, r.cfg, r.colTypes, r.rows, startedAt)
+			if err != nil {
+				return err
+			}
+			m.ResultSet = i + 1
+			m.ReturnValue = outputs.returnValue
+			m.Outputs = outputs.values
+			if err := fileio.WriteManifest(r.cfg.CSVPath, m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkProcColumnRules 检查每条脱敏规则和列值转换至少匹配一个结果集中的列
+func checkProcColumnRules(cols []string, cfg config.ExportConfig) error {
+	if cfg.Transforms != nil {
+		if err := cfg.Transforms.Check(cols); err != nil {
+			return err
+		}
+	}
+	return cfg.Masks.Check(cols)
+}
+
+// scanProcOutputs 读取批次最后一个结果集中的返回值和输出参数
+func scanProcOutputs(rows *sql.Rows, cols []string, binaryFormat string) (*procOutputs, error) {
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("获取列类型失败: %w", err)
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("读取存储过程返回值失败: %w", err)
+		}
+		return nil, fmt.Errorf("读取存储过程返回值失败: 结果集为空")
+	}
+
+	values := make([]interface{}, len(cols))
+	valuePtrs := make([]interface{}, len(cols))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return nil, fmt.Errorf("读取存储过程返回值失败: %w", err)
+	}
+
+	out := &procOutputs{}
+	if rv, ok := values[0].(int64); ok {
+		out.returnValue = &rv
+	}
+	if len(cols) > 1 {
+		out.names = cols[1:]
+		out.values = make(map[string]string, len(cols)-1)
+		for i := 1; i < len(cols); i++ {
+			out.values[cols[i]] = convertValueToString(values[i], colTypes[i].DatabaseTypeName(), binaryFormat)
+		}
+	}
+	return out, nil
+}
+
+// printProcOutputs 输出存储过程的返回值和输出参数
+func printProcOutputs(out *procOutputs) {
+	if out.returnValue != nil {
+		fmt.Printf("   返回值: %d\n", *out.returnValue)
+	}
+	for _, name := range out.names {
+		fmt.Printf("   @%s = %s\n", name, out.values[name])
+	}
+}
+
+// ResultSetPath 在文件名与扩展名之间插入结果集序号，如 report.csv.gz -> report_2.csv.gz
+func ResultSetPath(path string, n int) string {
+	dir, name := filepath.Split(path)
+	ext := ""
+	for {
+		e := filepath.Ext(name)
+		switch strings.ToLower(e) {
+		case ".csv", ".tsv", ".txt", ".gz", ".enc":
+			name, ext = strings.TrimSuffix(name, e), e+ext
+			continue
+		}
+		break
+	}
+	return fmt.Sprintf("%s%s_%d%s", dir, name, n, ext)
+}
@@ -0,0 +1,357 @@
+// exporter/tables.go
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/hooks"
+	"github.com/mssql_ie/retry"
+	"github.com/mssql_ie/utils"
+)
+
+// TableName 数据库中的表（架构名+表名）
+type TableName struct {
+	Schema string
+	Name   string
+}
+
+// String 返回 schema.table 形式的表名
+func (t TableName) String() string {
+	return t.Schema + "." + t.Name
+}
+
+// Quoted 返回转义后的 [schema].[table] 形式的表名
+func (t TableName) Quoted() string {
+	return utils.EscapeIdentifier(t.Schema) + "." + utils.EscapeIdentifier(t.Name)
+}
+
+// TableResult 单表导出结果
+type TableResult struct {
+	Table    TableName
+	Path     string
+	Rows     int
+	Duration time.Duration
+	Err      error
+}
+
+// TablesToDir 将匹配的多张表分别导出到输出目录，每张表一个 <schema>.<table>.csv 文件
+// 表按连接池大小并发导出，前后置SQL在单独的连接上只执行一次
+func TablesToDir(db *sql.DB, cfg config.ExportConfig) error {
+	if len(cfg.Tables) == 0 {
+		return fmt.Errorf("表匹配模式不能为空")
+	}
+	if cfg.OutDir == "" {
+		return fmt.Errorf("输出目录不能为空")
+	}
+	if err := os.MkdirAll(cfg.OutDir, 0755); err != nil {
+		return fmt.Errorf("创建输出目录失败: %w", err)
+	}
+
+	ctx := context.Background()
+	tables, err := ResolveTables(ctx, db, cfg.Tables, cfg.ExcludeTables)
+	if err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return fmt.Errorf("没有匹配的表: %s", strings.Join(cfg.Tables, ","))
+	}
+	fmt.Printf("共匹配 %d 张表\n", len(tables))
+
+	// 在写入任何文件之前检查脱敏和转换规则，避免列名拼写错误时敏感数据以明文导出
+	if err := checkTableColumnRules(ctx, db, tables, cfg); err != nil {
+		return err
+	}
+
+	// 前后置SQL使用单独的连接，只执行一次
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	// 有表导出失败时与单表导出一致，不执行后置SQL（除非指定 --post-sql-always）
+	return hooks.Wrap(ctx, conn, cfg.Hooks, func() error {
+		return PrintTableSummary(ExportTables(db, tables, cfg, Concurrency(db, 1)))
+	})
+}
+
+// checkTableColumnRules 检查每条脱敏规则和列值转换至少匹配一张导出表中的列
+func checkTableColumnRules(ctx context.Context, db *sql.DB, tables []TableName, cfg config.ExportConfig) error {
+	if cfg.Masks == nil && cfg.Transforms == nil {
+		return nil
+	}
+
+	var all []string
+	for _, t := range tables {
+		cols, err := tableColumns(ctx, db, t.Quoted())
+		if err != nil {
+			return err
+		}
+		all = append(all, cols...)
+	}
+
+	if cfg.Transforms != nil {
+		if err := cfg.Transforms.Check(all); err != nil {
+			return err
+		}
+	}
+	return cfg.Masks.Check(all)
+}
+
+// Concurrency 根据连接池大小计算并发数，reserved 为已占用的连接数
+func Concurrency(db *sql.DB, reserved int) int {
+	n := db.Stats().MaxOpenConnections
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+	n -= reserved
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// ExportTables 并发导出多张表，返回按表顺序排列的结果
+// 隔离级别为 snapshot 时在同一个快照事务中依次导出，保证各表数据一致
+func ExportTables(db *sql.DB, tables []TableName, cfg config.ExportConfig, workers int) []TableResult {
+	if strings.EqualFold(cfg.Isolation, "snapshot") && len(tables) > 1 {
+		return exportTablesSnapshot(db, tables, cfg)
+	}
+
+	results := make([]TableResult, len(tables))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+
+	for i, t := range tables {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, t TableName) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			tableCfg := tableConfig(cfg, t)
+			start := time.Now()
+			rows, err := exportTable(db, tableCfg)
+			results[i] = TableResult{
+				Table:    t,
+				Path:     tableCfg.CSVPath,
+				Rows:     rows,
+				Duration: time.Since(start),
+				Err:      err,
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// tableConfig 返回单张表的导出配置
+func tableConfig(cfg config.ExportConfig, t TableName) config.ExportConfig {
+	tableCfg := cfg
+	tableCfg.Table = t.Quoted()
+	tableCfg.CSVPath = filepath.Join(cfg.OutDir, tableFileName(t))
+	if cfg.Passphrase != "" {
+		tableCfg.CSVPath += ".enc"
+	}
+	tableCfg.Hooks = config.HookConfig{}
+	return tableCfg
+}
+
+// exportTablesSnapshot 在同一个快照事务中依次导出多张表
+// 任一表失败时快照随事务结束，剩余的表不再导出；暂时性错误时在新的快照中重新导出所有表
+func exportTablesSnapshot(db *sql.DB, tables []TableName, cfg config.ExportConfig) []TableResult {
+	fmt.Println("使用快照隔离在同一事务中依次导出所有表")
+	results := make([]TableResult, len(tables))
+	ctx := context.Background()
+	err := retry.Do(ctx, cfg.Retry, "快照导出", func() error {
+		for i, t := range tables {
+			results[i] = TableResult{Table: t, Path: tableConfig(cfg, t).CSVPath}
+		}
+
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("获取数据库连接失败: %w", err)
+		}
+		defer conn.Close()
+
+		return withIsolation(ctx, conn, cfg, func(q querier) error {
+			for i, t := range tables {
+				tableCfg := tableConfig(cfg, t)
+				query, err := buildTableQuery(tableCfg, nil)
+				if err != nil {
+					results[i].Err = err
+					return err
+				}
+
+				start := time.Now()
+				results[i].Rows, results[i].Err = writeQueryResult(ctx, q, query, tableCfg)
+				results[i].Duration = time.Since(start)
+				if results[i].Err != nil {
+					return fmt.Errorf("导出表 %s 失败: %w", t, results[i].Err)
+				}
+			}
+			return nil
+		})
+	})
+
+	// 快照事务中止时，未导出的表标记为失败
+	if err != nil {
+		for i := range results {
+			if results[i].Err == nil && results[i].Duration == 0 {
+				results[i].Err = fmt.Errorf("未导出（快照事务已中止: %v）", err)
+			}
+		}
+	}
+	return results
+}
+
+// exportTable 导出单张表（不执行前后置SQL），返回导出的行数
+func exportTable(db *sql.DB, cfg config.ExportConfig) (int, error) {
+	query, err := buildTableQuery(cfg, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// 遇到暂时性错误时在新连接上从头重新导出该表
+	ctx := context.Background()
+	var rows int
+	err = retry.Do(ctx, cfg.Retry, "导出表 "+cfg.Table, func() error {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("获取数据库连接失败: %w", err)
+		}
+		defer conn.Close()
+
+		return withIsolation(ctx, conn, cfg, func(q querier) error {
+			rows, err = writeQueryResult(ctx, q, query, cfg)
+			return err
+		})
+	})
+	return rows, err
+}
+
+// PrintTableSummary 输出每张表的导出结果，存在失败的表时返回错误
+func PrintTableSummary(results []TableResult) error {
+	fmt.Println("导出汇总:")
+	failed := 0
+	totalRows := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Printf("  ❌ %-40s %v\n", r.Table, r.Err)
+			continue
+		}
+		totalRows += r.Rows
+		fmt.Printf("  ✅ %-40s %10d 行  %8s  %s\n", r.Table, r.Rows, r.Duration.Round(time.Millisecond), r.Path)
+	}
+	fmt.Printf("共 %d 张表，成功 %d 张，失败 %d 张，合计 %d 行\n", len(results), len(results)-failed, failed, totalRows)
+
+	if failed > 0 {
+		return fmt.Errorf("%d 张表导出失败", failed)
+	}
+	return nil
+}
+
+// tableFileName 返回表对应的输出文件名 <schema>.<table>.csv
+func tableFileName(t TableName) string {
+	name := t.Schema + "." + t.Name + ".csv"
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// ResolveTables 从 INFORMATION_SCHEMA.TABLES 中查询与包含模式匹配且不与排除模式匹配的用户表
+// 模式形如 dbo.*、sales.Orders，支持 * 和 ? 通配符，不区分大小写；不带架构时默认为 dbo
+func ResolveTables(ctx context.Context, db *sql.DB, include, exclude []string) ([]TableName, error) {
+	if err := ValidatePatterns(append(append([]string{}, include...), exclude...)); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `
+		/* mssql_ie tool query for list tables*/
+		SELECT TABLE_SCHEMA, TABLE_NAME
+		FROM INFORMATION_SCHEMA.TABLES
+		WHERE TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_SCHEMA, TABLE_NAME
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("查询表列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []TableName
+	for rows.Next() {
+		var t TableName
+		if err := rows.Scan(&t.Schema, &t.Name); err != nil {
+			return nil, fmt.Errorf("解析表列表失败: %w", err)
+		}
+		if matchTable(t, include) && !matchTable(t, exclude) {
+			tables = append(tables, t)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历表列表异常: %w", err)
+	}
+	return tables, nil
+}
+
+// matchTable 判断表是否与任一模式匹配，模式须已通过 ValidatePatterns 校验
+func matchTable(t TableName, patterns []string) bool {
+	name := strings.ToLower(t.String())
+	for _, p := range patterns {
+		if p = normalizePattern(p); p == "" {
+			continue
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePattern 将模式转为小写，不带架构时补上 dbo
+func normalizePattern(p string) string {
+	p = strings.ToLower(strings.TrimSpace(p))
+	if p != "" && !strings.Contains(p, ".") {
+		p = "dbo." + p
+	}
+	return p
+}
+
+// ValidatePatterns 校验表匹配模式的语法
+// 模式按 path.Match 解释：[...] 为字符类，表名中的 [、]、*、? 和 \ 需用 \ 转义
+func ValidatePatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(normalizePattern(p), ""); err != nil {
+			return fmt.Errorf("无效的表匹配模式 %q（表名中的 [ 需写为 \\[）: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// SplitPatterns 拆分逗号分隔的表匹配模式
+func SplitPatterns(values []string) []string {
+	var patterns []string
+	for _, v := range values {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,40 @@
+// exporter/tables_test.go
+package exporter
+
+import (
+	"errors"
+	"path"
+	"testing"
+)
+
+func TestMatchTable(t *testing.T) {
+	tests := []struct {
+		table    TableName
+		patterns []string
+		want     bool
+	}{
+		{TableName{"dbo", "Orders"}, []string{"orders"}, true},
+		{TableName{"sales", "Orders"}, []string{"orders"}, false},
+		{TableName{"sales", "Orders"}, []string{"sales.*"}, true},
+		{TableName{"dbo", "order_log"}, []string{"dbo.*_log"}, true},
+		{TableName{"dbo", "t1"}, []string{"dbo.t[0-9]"}, true},
+		{TableName{"dbo", "[old]orders"}, []string{`dbo.\[old\]*`}, true},
+		{TableName{"dbo", "o"}, []string{`dbo.[old]`}, true},
+		{TableName{"dbo", "[old]"}, []string{`dbo.[old]`}, false},
+	}
+	for _, tt := range tests {
+		if got := matchTable(tt.table, tt.patterns); got != tt.want {
+			t.Errorf("matchTable(%s, %v) = %v，期望 %v", tt.table, tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePatterns(t *testing.T) {
+	if err := ValidatePatterns([]string{"dbo.*", `dbo.\[abc`, "sales.t[0-9]"}); err != nil {
+		t.Errorf("有效模式校验失败: %v", err)
+	}
+	err := ValidatePatterns([]string{"dbo.*", "dbo.[abc"})
+	if !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("未闭合的 [ 返回 %v，期望 path.ErrBadPattern", err)
+	}
+}
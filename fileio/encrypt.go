@@ -0,0 +1,203 @@
+// fileio/encrypt.go
+package fileio
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// 加密文件格式:
+//
+//	magic(8) | logN(1) | salt(16) | noncePrefix(7) | chunk...
+//
+// 密钥由口令经 scrypt(N=2^logN, r=8, p=1) 派生，每个块使用 AES-256-GCM 加密，
+// nonce 为 noncePrefix | 块序号(4, 大端) | 末块标志(1)，可防止块被重排或截断
+const (
+	encMagic       = "MSIEENC1"
+	encLogN        = 15
+	encSaltSize    = 16
+	encPrefixSize  = 7
+	encChunkSize   = 64 * 1024
+	encHeaderSize  = len(encMagic) + 1 + encSaltSize + encPrefixSize
+	encOverhead    = 16 // GCM 认证标签长度
+	scryptR        = 8
+	scryptP        = 1
+	encryptKeySize = 32
+)
+
+// ErrPassphrase 口令错误或文件已损坏
+var ErrPassphrase = errors.New("解密失败: 口令错误或文件已损坏")
+
+// deriveKey 由口令和盐派生 AES-256-GCM 实例
+func deriveKey(passphrase string, salt []byte, logN byte) (cipher.AEAD, error) {
+	if logN < 10 || logN > 22 {
+		return nil, fmt.Errorf("不支持的加密参数 logN=%d", logN)
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<logN, scryptR, scryptP, encryptKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce 生成第 n 块的 nonce
+func chunkNonce(prefix []byte, n uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encPrefixSize:], n)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// encryptWriter 分块加密写入
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	prefix []byte
+	buf    []byte
+	n      uint32
+	closed bool
+}
+
+// newEncryptWriter 写入文件头并返回加密写入器，Close 时写入末块
+func newEncryptWriter(w io.Writer, passphrase string) (*encryptWriter, error) {
+	header := make([]byte, encHeaderSize)
+	copy(header, encMagic)
+	header[len(encMagic)] = encLogN
+	if _, err := rand.Read(header[len(encMagic)+1:]); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %w", err)
+	}
+	salt := header[len(encMagic)+1 : len(encMagic)+1+encSaltSize]
+	prefix := header[len(encMagic)+1+encSaltSize:]
+
+	aead, err := deriveKey(passphrase, salt, encLogN)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, encChunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// 缓冲区已满且还有数据时才写出，保证末块在 Close 时写出
+		if len(e.buf) == encChunkSize {
+			if err := e.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):encChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// flush 加密并写出缓冲区中的数据
+func (e *encryptWriter) flush(last bool) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.prefix, e.n, last), e.buf, nil)
+	if _, err := e.w.Write(sealed); err != nil {
+		return err
+	}
+	e.n++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// Close 写出末块，不关闭底层写入器
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.flush(true)
+}
+
+// decryptReader 分块解密读取
+type decryptReader struct {
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	chunk  []byte
+	plain  []byte
+	n      uint32
+	done   bool
+}
+
+// isEncrypted 判断数据是否以加密文件头开始
+func isEncrypted(head []byte) bool {
+	return bytes.HasPrefix(head, []byte(encMagic))
+}
+
+// newDecryptReader 读取文件头并返回解密读取器
+func newDecryptReader(r *bufio.Reader, passphrase string) (*decryptReader, error) {
+	header := make([]byte, encHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("读取加密文件头失败: %w", err)
+	}
+	logN := header[len(encMagic)]
+	salt := header[len(encMagic)+1 : len(encMagic)+1+encSaltSize]
+	prefix := header[len(encMagic)+1+encSaltSize:]
+
+	aead, err := deriveKey(passphrase, salt, logN)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: r, aead: aead, prefix: prefix, chunk: make([]byte, encChunkSize+encOverhead)}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// next 读取并解密下一块
+func (d *decryptReader) next() error {
+	n, err := io.ReadFull(d.r, d.chunk)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	// 块不满或其后没有数据时为末块
+	last := n < len(d.chunk)
+	if !last {
+		if _, err := d.r.Peek(1); err == io.EOF {
+			last = true
+		}
+	}
+	plain, err := d.aead.Open(d.chunk[:0:0], chunkNonce(d.prefix, d.n, last), d.chunk[:n], nil)
+	if err != nil {
+		return ErrPassphrase
+	}
+	d.plain = plain
+	d.n++
+	d.done = last
+	return nil
+}
@@ -0,0 +1,92 @@
+// fileio/encrypt_test.go
+package fileio
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+// encrypt 加密 plain 并返回完整的加密文件内容
+func encrypt(t *testing.T, plain []byte, passphrase string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := newEncryptWriter(&buf, passphrase)
+	if err != nil {
+		t.Fatalf("创建加密写入器失败: %v", err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("关闭失败: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// decrypt 解密完整的加密文件内容
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+	r, err := newDecryptReader(bufio.NewReader(bytes.NewReader(data)), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	sizes := []int{0, 1, encChunkSize - 1, encChunkSize, encChunkSize + 1, 2 * encChunkSize}
+	for _, size := range sizes {
+		plain := make([]byte, size)
+		if _, err := rand.Read(plain); err != nil {
+			t.Fatal(err)
+		}
+		data := encrypt(t, plain, "secret")
+		if !isEncrypted(data) {
+			t.Errorf("大小 %d: 加密文件缺少文件头", size)
+		}
+		got, err := decrypt(data, "secret")
+		if err != nil {
+			t.Fatalf("大小 %d: 解密失败: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("大小 %d: 解密结果与原文不一致（长度 %d）", size, len(got))
+		}
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	// 三个块：两个满块和一个 1 字节的末块
+	plain := bytes.Repeat([]byte("0123456789abcdef"), (2*encChunkSize)/16+1)[:2*encChunkSize+1]
+	data := encrypt(t, plain, "secret")
+	sealed := encChunkSize + encOverhead
+	header, chunks := data[:encHeaderSize], data[encHeaderSize:]
+	if len(chunks) != 2*sealed+1+encOverhead {
+		t.Fatalf("加密数据长度 = %d，期望 %d", len(chunks), 2*sealed+1+encOverhead)
+	}
+
+	swapped := append([]byte{}, header...)
+	swapped = append(swapped, chunks[sealed:2*sealed]...)
+	swapped = append(swapped, chunks[:sealed]...)
+	swapped = append(swapped, chunks[2*sealed:]...)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+	}{
+		{"口令错误", data, "wrong"},
+		{"删除末块", data[:encHeaderSize+2*sealed], "secret"},
+		{"截断末块", data[:len(data)-1], "secret"},
+		{"交换数据块", swapped, "secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decrypt(tt.data, tt.passphrase); !errors.Is(err, ErrPassphrase) {
+				t.Errorf("错误 = %v，期望 ErrPassphrase", err)
+			}
+		})
+	}
+}
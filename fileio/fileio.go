@@ -0,0 +1,136 @@
+// Package fileio 负责导入导出文件的打开与创建，按需叠加 gzip 压缩和口令加密
+// 写入顺序为 CSV -> 字符集转换 -> gzip -> 加密 -> 文件，读取时按相反顺序处理
+package fileio
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// gzip 文件头
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// CreateOptions 创建输出文件的选项
+type CreateOptions struct {
+	// Passphrase 非空时加密输出文件
+	Passphrase string
+}
+
+// IsCompressed 根据扩展名判断输出是否需要压缩（.gz 或 .gz.enc）
+func IsCompressed(path string) bool {
+	lower := strings.TrimSuffix(strings.ToLower(path), ".enc")
+	return strings.HasSuffix(lower, ".gz")
+}
+
+// writer 依次关闭各层写入器
+type writer struct {
+	io.Writer
+	closers []io.Closer
+	closed  bool
+}
+
+// Close 从内到外依次关闭各层，返回第一个错误
+func (w *writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	var first error
+	for _, c := range w.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Create 创建输出文件，文件名以 .gz（或 .gz.enc）结尾时压缩，设置口令时加密
+// 调用方必须调用 Close 并检查错误，否则压缩和加密的末尾数据不会写出
+func Create(path string, opts CreateOptions) (io.WriteCloser, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &writer{Writer: file, closers: []io.Closer{file}}
+	if opts.Passphrase != "" {
+		enc, err := newEncryptWriter(file, opts.Passphrase)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("初始化加密失败: %w", err)
+		}
+		w.Writer = enc
+		w.closers = append([]io.Closer{enc}, w.closers...)
+	}
+	if IsCompressed(path) {
+		gz := gzip.NewWriter(w.Writer)
+		w.Writer = gz
+		w.closers = append([]io.Closer{gz}, w.closers...)
+	}
+	return w, nil
+}
+
+// reader 读取结束后关闭文件和解压缩器
+type reader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *reader) Close() error {
+	var first error
+	for _, c := range r.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Open 打开输入文件，根据文件头自动识别并解密、解压缩
+// 文件已加密但未提供口令时返回错误
+func Open(path string, passphrase string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &reader{closers: []io.Closer{file}}
+	br := bufio.NewReader(file)
+	head, _ := br.Peek(len(encMagic))
+	if isEncrypted(head) {
+		if passphrase == "" {
+			file.Close()
+			return nil, fmt.Errorf("文件 %s 已加密，请通过 --passphrase 或环境变量 MSSQL_FILE_PASSPHRASE 提供口令", path)
+		}
+		dec, err := newDecryptReader(br, passphrase)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		br = bufio.NewReader(dec)
+		// 预读首块，口令错误时在打开阶段即报错
+		if _, err := br.Peek(1); err != nil && err != io.EOF {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	head, _ = br.Peek(len(gzipMagic))
+	if len(head) == len(gzipMagic) && head[0] == gzipMagic[0] && head[1] == gzipMagic[1] {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("读取压缩文件失败: %w", err)
+		}
+		r.Reader = gz
+		r.closers = append([]io.Closer{gz}, r.closers...)
+		return r, nil
+	}
+
+	r.Reader = br
+	return r, nil
+}
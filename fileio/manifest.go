@@ -0,0 +1,159 @@
+// fileio/manifest.go
+package fileio
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mssql_ie/utils"
+)
+
+// ManifestVersion 导出清单格式版本
+const ManifestVersion = 1
+
+// ManifestSuffix 导出清单文件后缀，清单与数据文件位于同一目录
+const ManifestSuffix = ".manifest.json"
+
+// Manifest 单个导出文件的清单，供接收方校验文件完整性
+type Manifest struct {
+	Version    int              `json:"version"`
+	File       string           `json:"file"`
+	Query      string           `json:"query"`
+	Server     string           `json:"server,omitempty"`
+	Database   string           `json:"database,omitempty"`
+	Columns    []ManifestColumn `json:"columns"`
+	Rows       int              `json:"rows"`
+	Bytes      int64            `json:"bytes"`
+	SHA256     string           `json:"sha256"`
+	Header     bool             `json:"header"`
+	Delimiter  string           `json:"delimiter"`
+	Charset    string           `json:"charset"`
+	Compressed bool             `json:"compressed,omitempty"`
+	Encrypted  bool             `json:"encrypted,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+	// 存储过程导出时记录文件对应的结果集序号（从 1 开始）、返回值和输出参数
+	ResultSet   int               `json:"result_set,omitempty"`
+	ReturnValue *int64            `json:"return_value,omitempty"`
+	Outputs     map[string]string `json:"outputs,omitempty"`
+}
+
+// ManifestColumn 导出文件中的列
+type ManifestColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ManifestPath 返回数据文件对应的清单文件路径
+func ManifestPath(path string) string {
+	return path + ManifestSuffix
+}
+
+// WriteManifest 将清单写入数据文件旁的 .manifest.json 文件
+func WriteManifest(path string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化导出清单失败: %w", err)
+	}
+	if err := os.WriteFile(ManifestPath(path), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("写入导出清单失败: %w", err)
+	}
+	return nil
+}
+
+// ReadManifest 读取数据文件对应的清单
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(ManifestPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("读取导出清单失败: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("解析导出清单 %s 失败: %w", ManifestPath(path), err)
+	}
+	if m.Version > ManifestVersion {
+		return nil, fmt.Errorf("不支持的导出清单版本 %d", m.Version)
+	}
+	return &m, nil
+}
+
+// Checksum 计算文件的大小和 SHA-256
+func Checksum(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyManifest 按清单校验数据文件的大小、SHA-256 和数据行数，不一致时返回错误
+func VerifyManifest(path, passphrase string) error {
+	m, err := ReadManifest(path)
+	if err != nil {
+		return err
+	}
+
+	size, sum, err := Checksum(path)
+	if err != nil {
+		return fmt.Errorf("计算文件校验和失败: %w", err)
+	}
+	if size != m.Bytes {
+		return fmt.Errorf("文件 %s 大小 %d 与清单记录的 %d 不一致", path, size, m.Bytes)
+	}
+	if sum != m.SHA256 {
+		return fmt.Errorf("文件 %s 的 SHA-256 与清单记录不一致", path)
+	}
+
+	rows, err := countRows(path, passphrase, m)
+	if err != nil {
+		return fmt.Errorf("统计文件行数失败: %w", err)
+	}
+	if rows != m.Rows {
+		return fmt.Errorf("文件 %s 数据行数 %d 与清单记录的 %d 不一致", path, rows, m.Rows)
+	}
+
+	fmt.Printf("已按清单校验文件 %s: %d 行，%d 字节\n", path, rows, size)
+	return nil
+}
+
+// countRows 按清单中记录的格式统计数据行数（不含标题行）
+func countRows(path, passphrase string, m *Manifest) (int, error) {
+	file, err := Open(path, passphrase)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(utils.GetTransformersRead(file, m.Charset))
+	if d := []rune(m.Delimiter); len(d) > 0 {
+		reader.Comma = d[0]
+	}
+	reader.FieldsPerRecord = -1
+
+	rows := 0
+	for {
+		if _, err := reader.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+		rows++
+	}
+	if m.Header && rows > 0 {
+		rows--
+	}
+	return rows, nil
+}
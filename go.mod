@@ -2,17 +2,19 @@ module github.com/mssql_ie
 
 go 1.24.0
 
-require github.com/microsoft/go-mssqldb v1.9.5
+require (
+	github.com/golang-sql/civil v0.0.0-20220223132316-b832511892a9
+	github.com/microsoft/go-mssqldb v1.9.5
+	golang.org/x/crypto v0.38.0
+)
 
 require (
 	github.com/cpuguy83/go-md2man/v2 v2.0.7 // indirect
-	github.com/golang-sql/civil v0.0.0-20220223132316-b832511892a9 // indirect
 	github.com/golang-sql/sqlexp v0.1.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/shopspring/decimal v1.4.0 // indirect
 	github.com/urfave/cli/v2 v2.27.7 // indirect
 	github.com/xrash/smetrics v0.0.0-20240521201337-686a1a2994c1 // indirect
-	golang.org/x/crypto v0.38.0 // indirect
 	golang.org/x/text v0.25.0 // indirect
 )
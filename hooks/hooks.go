@@ -0,0 +1,79 @@
+// Package hooks 在导入导出前后执行自定义SQL（前置/后置钩子）
+package hooks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/microsoft/go-mssqldb/batch"
+
+	"github.com/mssql_ie/config"
+)
+
+// Load 解析 --pre-sql / --post-sql 参数
+// 每个参数可以是内联SQL，也可以是 @file.sql 形式的脚本文件；
+// 脚本按 GO 分隔符拆分为多个批次，空批次会被忽略
+func Load(specs []string) ([]string, error) {
+	var batches []string
+	for _, spec := range specs {
+		script := spec
+		if strings.HasPrefix(spec, "@") {
+			data, err := os.ReadFile(spec[1:])
+			if err != nil {
+				return nil, fmt.Errorf("读取SQL脚本失败: %w", err)
+			}
+			script = string(data)
+		}
+		batches = append(batches, SplitBatches(script)...)
+	}
+	return batches, nil
+}
+
+// SplitBatches 按 GO 分隔符拆分SQL脚本，并去掉空批次
+func SplitBatches(script string) []string {
+	var batches []string
+	for _, b := range batch.Split(script, "GO") {
+		if strings.TrimSpace(b) != "" {
+			batches = append(batches, b)
+		}
+	}
+	return batches
+}
+
+// Run 在指定连接上依次执行SQL批次
+func Run(ctx context.Context, conn *sql.Conn, stage string, batches []string) error {
+	for i, b := range batches {
+		if _, err := conn.ExecContext(ctx, b); err != nil {
+			return fmt.Errorf("执行%sSQL失败(批次%d): %w", stage, i+1, err)
+		}
+	}
+	if len(batches) > 0 {
+		fmt.Printf("已执行%sSQL %d 个批次\n", stage, len(batches))
+	}
+	return nil
+}
+
+// Wrap 在同一连接上依次执行前置SQL、fn 和后置SQL
+// 前置SQL或 fn 失败时默认不执行后置SQL；cfg.PostSQLAlways 为 true 时
+// 无论成功与否都会执行后置SQL（例如重新启用被禁用的索引和触发器）
+func Wrap(ctx context.Context, conn *sql.Conn, cfg config.HookConfig, fn func() error) error {
+	err := Run(ctx, conn, "前置", cfg.PreSQL)
+	if err == nil {
+		err = fn()
+	}
+
+	if err != nil && !cfg.PostSQLAlways {
+		return err
+	}
+
+	if postErr := Run(ctx, conn, "后置", cfg.PostSQL); postErr != nil {
+		if err != nil {
+			return fmt.Errorf("%w（且%v）", err, postErr)
+		}
+		return postErr
+	}
+	return err
+}
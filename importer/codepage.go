@@ -0,0 +1,116 @@
+// importer/codepage.go
+package importer
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// codePageUTF8 UTF-8 排序规则（_UTF8）的代码页
+const codePageUTF8 = 65001
+
+// codePageEncodings char/varchar 列常见排序规则代码页对应的编码
+var codePageEncodings = map[int]encoding.Encoding{
+	437:  charmap.CodePage437,
+	850:  charmap.CodePage850,
+	874:  charmap.Windows874,
+	932:  japanese.ShiftJIS,
+	936:  simplifiedchinese.GBK,
+	949:  korean.EUCKR,
+	950:  traditionalchinese.Big5,
+	1250: charmap.Windows1250,
+	1251: charmap.Windows1251,
+	1252: charmap.Windows1252,
+	1253: charmap.Windows1253,
+	1254: charmap.Windows1254,
+	1255: charmap.Windows1255,
+	1256: charmap.Windows1256,
+	1257: charmap.Windows1257,
+	1258: charmap.Windows1258,
+}
+
+// encodedLen 返回字符串按列排序规则的代码页编码后的字节数（char/varchar 的长度单位）
+// 代码页未知时返回 false，由数据库判断是否超长
+func encodedLen(col ColumnInfo, s string) (int, bool) {
+	if col.CodePage == codePageUTF8 {
+		return len(s), true
+	}
+	enc, ok := codePageEncodings[col.CodePage]
+	if !ok {
+		return 0, false
+	}
+	n := 0
+	encoder := encoding.ReplaceUnsupported(enc.NewEncoder())
+	for _, r := range s {
+		n += runeLen(encoder, r)
+	}
+	return n, true
+}
+
+// truncateEncoded 按列排序规则的代码页截断字符串，使编码后不超过 n 字节，不截断多字节字符
+func truncateEncoded(col ColumnInfo, s string, n int) string {
+	if col.CodePage == codePageUTF8 {
+		return truncateBytes(s, n)
+	}
+	enc, ok := codePageEncodings[col.CodePage]
+	if !ok {
+		return s
+	}
+	size := 0
+	encoder := encoding.ReplaceUnsupported(enc.NewEncoder())
+	for i, r := range s {
+		size += runeLen(encoder, r)
+		if size > n {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// runeLen 返回单个字符编码后的字节数；代码页中不存在的字符由数据库替换为单字节的 ?
+func runeLen(encoder *encoding.Encoder, r rune) int {
+	if r < 0x80 {
+		return 1
+	}
+	b, err := encoder.String(string(r))
+	if err != nil || len(b) == 0 {
+		return 1
+	}
+	return len(b)
+}
+
+// collationKey 返回字符串在排序规则下用于判断相等的形式
+// 不区分大小写（_CI）的排序规则折叠大小写，不区分重音（_AI）的排序规则去掉重音符号；
+// 二进制排序规则（_BIN、_BIN2）和未知排序规则按原值比较
+func collationKey(collation, s string) string {
+	var ci, ai bool
+	for _, part := range strings.Split(strings.ToUpper(collation), "_") {
+		switch part {
+		case "CI":
+			ci = true
+		case "AI":
+			ai = true
+		}
+	}
+	if ai {
+		t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+		if out, _, err := transform.String(t, s); err == nil {
+			s = out
+		}
+	}
+	if ci {
+		s = cases.Fold().String(s)
+	}
+	return s
+}
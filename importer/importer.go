@@ -2,28 +2,186 @@
 package importer
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/fileio"
+	"github.com/mssql_ie/hooks"
+	"github.com/mssql_ie/retry"
+	"github.com/mssql_ie/transforms"
 	"github.com/mssql_ie/utils"
 )
 
 // CSVToTable 从CSV文件导入数据到指定表
 func CSVToTable(db *sql.DB, cfg config.ImportConfig) error {
+	return FilesToTable(db, cfg, []string{cfg.CSVPath})
+}
+
+// FilesToTable 按顺序将多个CSV文件导入到同一张表
+// 前后置SQL与 --truncate 只执行一次；原子模式下所有文件在同一个事务中导入
+func FilesToTable(db *sql.DB, cfg config.ImportConfig, files []string) error {
 	// 参数校验
 	if err := validateImportConfig(cfg); err != nil {
 		return fmt.Errorf("配置校验失败: %w", err)
 	}
+	if len(files) == 0 {
+		return fmt.Errorf("没有需要导入的CSV文件")
+	}
+
+	// 任一文件与清单不一致时不导入任何文件
+	if cfg.VerifyManifest {
+		for _, path := range files {
+			if err := fileio.VerifyManifest(path, cfg.Passphrase); err != nil {
+				return fmt.Errorf("清单校验失败: %w", err)
+			}
+		}
+	}
+
+	// 获取专用连接，保证前后置SQL与导入在同一会话中执行
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	// 校验模式不执行前后置SQL，避免修改任何数据
+	if cfg.DryRun {
+		return validateFiles(ctx, conn, cfg, files)
+	}
+
+	return hooks.Wrap(ctx, conn, cfg.Hooks, func() error {
+		return loadFiles(ctx, conn, cfg, files)
+	})
+}
+
+// loadFiles 在指定连接上依次导入多个CSV文件
+func loadFiles(ctx context.Context, conn *sql.Conn, cfg config.ImportConfig, files []string) error {
+	// 获取表结构
+	columnInfos, err := getTableColumns(ctx, conn, cfg.Table)
+	if err != nil {
+		return fmt.Errorf("获取表列名失败: %w", err)
+	}
+
+	// 原子模式下所有文件共用一个事务，任何失败都会整体回滚
+	var atomicTx *sql.Tx
+	if cfg.Atomic {
+		atomicTx, err = conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("开启事务失败: %w", err)
+		}
+		defer atomicTx.Rollback()
+	}
+
+	// 如果需要，先清空表（原子模式下在导入事务内清空）
+	if cfg.Truncate {
+		var db queryer = conn
+		if atomicTx != nil {
+			db = atomicTx
+		}
+		if err := truncateTable(ctx, db, cfg.Table); err != nil {
+			return fmt.Errorf("清空表失败: %w", err)
+		}
+	}
+
+	// 记录导入前的行数（和校验和）用于导入后核对
+	var rec *reconciler
+	if cfg.Reconcile {
+		var db queryer = conn
+		if atomicTx != nil {
+			db = atomicTx
+		}
+		if rec, err = newReconciler(ctx, db, cfg, columnInfos); err != nil {
+			return err
+		}
+	}
+
+	// 被拒绝的行写入同一个拒绝文件
+	rejects, err := newRejectWriter(cfg.RejectPath, cfg.Delimiter)
+	if err != nil {
+		return err
+	}
+	if rejects != nil {
+		defer rejects.Close()
+	}
+
+	total := 0
+	for i, path := range files {
+		if len(files) > 1 {
+			fmt.Printf("[%d/%d] 正在导入文件 %s\n", i+1, len(files), path)
+		}
+		count, err := loadFile(ctx, conn, atomicTx, rejects, cfg, columnInfos, path)
+		if err != nil {
+			if len(files) > 1 {
+				return fmt.Errorf("导入文件 %s 失败: %w", path, err)
+			}
+			return err
+		}
+		total += count
+
+		// 非原子且不核对时每个文件导入完成后立即归档；
+		// 核对校验和时需要重新读取原文件，且核对失败时文件应留在原处，因此核对通过后再归档
+		if atomicTx == nil && rec == nil {
+			if err := archiveFile(path, cfg.ArchiveDir); err != nil {
+				return err
+			}
+		}
+	}
 
-	// 打开CSV文件
-	file, err := os.Open(cfg.CSVPath)
+	if rec != nil {
+		if err := rec.verify(ctx, conn, atomicTx, cfg, columnInfos, files, total); err != nil {
+			return err
+		}
+	}
+
+	if atomicTx != nil {
+		if err := atomicTx.Commit(); err != nil {
+			return fmt.Errorf("提交事务失败: %w", err)
+		}
+	}
+	if atomicTx != nil || rec != nil {
+		for _, path := range files {
+			if err := archiveFile(path, cfg.ArchiveDir); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(files) > 1 {
+		fmt.Printf("✅ 共导入 %d 个文件，合计 %d 行数据\n", len(files), total)
+	}
+	return nil
+}
+
+// LoadFileOnConn 在指定连接上将 cfg.CSVPath 导入 cfg.Table，返回插入的行数
+// 列定义取自 columnsFrom 表，用于导入临时表等在 INFORMATION_SCHEMA 中查不到的表；
+// 不执行前后置SQL和 --truncate
+func LoadFileOnConn(ctx context.Context, conn *sql.Conn, cfg config.ImportConfig, columnsFrom string) (int, error) {
+	if err := validateImportConfig(cfg); err != nil {
+		return 0, fmt.Errorf("配置校验失败: %w", err)
+	}
+	columnInfos, err := getTableColumns(ctx, conn, columnsFrom)
 	if err != nil {
-		return fmt.Errorf("打开CSV文件失败: %w", err)
+		return 0, fmt.Errorf("获取表列名失败: %w", err)
+	}
+	return loadFile(ctx, conn, nil, nil, cfg, columnInfos, cfg.CSVPath)
+}
+
+// loadFile 导入单个CSV文件，返回插入的行数
+// rejects 为空时不记录被拒绝的行
+func loadFile(ctx context.Context, conn *sql.Conn, atomicTx *sql.Tx, rejects *rejectWriter, cfg config.ImportConfig, columnInfos []ColumnInfo, path string) (int, error) {
+	// 打开CSV文件（自动解密和解压缩）
+	file, err := fileio.Open(path, cfg.Passphrase)
+	if err != nil {
+		return 0, fmt.Errorf("打开CSV文件失败: %w", err)
 	}
 	defer file.Close()
 
@@ -31,64 +189,187 @@ func CSVToTable(db *sql.DB, cfg config.ImportConfig) error {
 	reader := csv.NewReader(utils.GetTransformersRead(file, cfg.FileCharset))
 	reader.Comma = cfg.Delimiter
 
-	// 读取列名
-	var columnInfos []ColumnInfo
-	// 如果没有标题行，尝试从数据库获取列名
-	columnInfos, err = getTableColumns(db, cfg.Table)
+	plan, err := buildInsertPlan(reader, columnInfos, path, cfg)
 	if err != nil {
-		return fmt.Errorf("获取表列名失败: %w", err)
+		return 0, err
 	}
-	var headerRow []string
-	var insertCols = make([]ColumnInfo, 0, len(columnInfos))
+	if rejects != nil {
+		if err := rejects.WriteHeader(plan.Header); err != nil {
+			return 0, fmt.Errorf("写入拒绝文件失败: %w", err)
+		}
+		plan.Rejects = rejects
+	}
+
+	// 开始事务批量插入
+	return batchInsert(ctx, conn, atomicTx, plan, reader, cfg)
+}
+
+// insertPlan 单个文件的插入计划
+type insertPlan struct {
+	SQL       string              // 参数化插入语句
+	Columns   []ColumnInfo        // 插入列，与 Fields 一一对应
+	Fields    []int               // 插入列在CSV行中的位置
+	Width     int                 // CSV行的列数
+	ExtraArgs []interface{}       // 追加在每行末尾的固定参数（如来源文件名）
+	Header    []string            // CSV标题行，无标题时为空
+	Chains    []*transforms.Chain // 插入列的值转换，与 Columns 一一对应，未配置时为 nil
+	Rejects   *rejectWriter       // 被拒绝行的输出，为空时不记录
+}
+
+// buildInsertPlan 根据CSV标题行（或表结构）确定插入列及其在CSV行中的位置
+// CSV中的计算列和 rowversion 列会被忽略，来源文件列由工具填充
+func buildInsertPlan(reader *csv.Reader, columnInfos []ColumnInfo, path string, cfg config.ImportConfig) (*insertPlan, error) {
+	// 来源文件列由工具填充，不参与CSV列匹配
+	fileCols := make([]ColumnInfo, 0, len(columnInfos))
+	for _, col := range columnInfos {
+		if cfg.SourceFileColumn != "" && strings.EqualFold(col.Name, cfg.SourceFileColumn) {
+			continue
+		}
+		fileCols = append(fileCols, col)
+	}
+	if cfg.SourceFileColumn != "" && len(fileCols) == len(columnInfos) {
+		return nil, fmt.Errorf("来源文件列 %s 在表 %s 中不存在", cfg.SourceFileColumn, cfg.Table)
+	}
+
+	plan := &insertPlan{}
 	if cfg.Header {
-		headerRow, err = reader.Read()
+		// 读取列名
+		headerRow, err := reader.Read()
 		if err != nil {
-			return fmt.Errorf("读取CSV列名失败: %w", err)
+			return nil, fmt.Errorf("读取CSV列名失败: %w", err)
 		}
+		plan.Width = len(headerRow)
+		plan.Header = headerRow
+
 		// 检查CSV列名是否与数据库列名匹配
-		if len(headerRow) != len(columnInfos) {
-			return fmt.Errorf("CSV列数 %d 与数据库列数 %d 不匹配", len(headerRow), len(columnInfos))
-		}
-		for _, col := range headerRow {
+		matched := make(map[string]bool, len(headerRow))
+		for i, col := range headerRow {
 			found := false
-			for _, dbCol := range columnInfos {
+			for _, dbCol := range fileCols {
 				if strings.EqualFold(col, dbCol.Name) {
-					insertCols = append(insertCols, dbCol)
+					if !dbCol.Computed {
+						plan.Columns = append(plan.Columns, dbCol)
+						plan.Fields = append(plan.Fields, i)
+					}
+					matched[strings.ToLower(dbCol.Name)] = true
 					found = true
 					break
 				}
 			}
 			if !found {
-				return fmt.Errorf("CSV列 %s 与数据库列名不匹配", col)
+				return nil, fmt.Errorf("CSV列 %s 与数据库列名不匹配", col)
+			}
+		}
+		for _, dbCol := range fileCols {
+			if !dbCol.Computed && !matched[strings.ToLower(dbCol.Name)] {
+				return nil, fmt.Errorf("CSV列数 %d 与数据库列数 %d 不匹配，缺少列 %s", len(headerRow), len(fileCols), dbCol.Name)
 			}
 		}
 	} else {
-		headerRow = make([]string, len(columnInfos))
-		for i, col := range columnInfos {
-			headerRow[i] = col.Name
+		// 如果没有标题行，按表结构顺序对应可插入的列
+		for _, dbCol := range fileCols {
+			if dbCol.Computed {
+				continue
+			}
+			plan.Fields = append(plan.Fields, len(plan.Columns))
+			plan.Columns = append(plan.Columns, dbCol)
+		}
+		plan.Width = len(plan.Columns)
+	}
+
+	// 值转换只能作用于插入列
+	if cfg.Transforms != nil {
+		names := make([]string, len(plan.Columns))
+		plan.Chains = make([]*transforms.Chain, len(plan.Columns))
+		for i, col := range plan.Columns {
+			names[i] = col.Name
+			plan.Chains[i] = cfg.Transforms.For(col.Name)
+		}
+		if err := cfg.Transforms.Check(names); err != nil {
+			return nil, err
 		}
 	}
 
 	// 安全地转义列名
-	safeCols := make([]string, len(headerRow))
-	for i, col := range headerRow {
-		safeCols[i] = utils.EscapeIdentifier(col)
+	safeCols := make([]string, len(plan.Columns), len(plan.Columns)+1)
+	for i, col := range plan.Columns {
+		safeCols[i] = utils.EscapeIdentifier(col.Name)
+	}
+
+	// 来源文件列追加在末尾，值为文件名
+	if cfg.SourceFileColumn != "" {
+		safeCols = append(safeCols, utils.EscapeIdentifier(cfg.SourceFileColumn))
+		plan.ExtraArgs = append(plan.ExtraArgs, filepath.Base(path))
 	}
 
 	// 构建插入SQL
 	insertSQL, err := buildInsertSQL(cfg.Table, safeCols)
 	if err != nil {
-		return fmt.Errorf("构建插入SQL失败: %w", err)
+		return nil, fmt.Errorf("构建插入SQL失败: %w", err)
 	}
+	plan.SQL = insertSQL
+	return plan, nil
+}
 
-	// 如果需要，先清空表
-	if cfg.Truncate {
-		if err := truncateTable(db, cfg.Table); err != nil {
-			return fmt.Errorf("清空表失败: %w", err)
+// transform 对第 i 个插入列的值应用转换，未配置转换时原样返回
+func (p *insertPlan) transform(i int, value string) (string, error) {
+	if p.Chains == nil || p.Chains[i] == nil {
+		return value, nil
+	}
+	return p.Chains[i].Apply(value)
+}
+
+// archiveFile 将导入完成的文件移动到归档目录，archiveDir 为空时不处理
+func archiveFile(path, archiveDir string) error {
+	if archiveDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return fmt.Errorf("创建归档目录失败: %w", err)
+	}
+	target := filepath.Join(archiveDir, filepath.Base(path))
+	if err := os.Rename(path, target); err != nil {
+		return fmt.Errorf("归档文件 %s 失败: %w", path, err)
+	}
+	fmt.Printf("已归档文件: %s -> %s\n", path, target)
+	return nil
+}
+
+// inputExts 目录导入时识别的文件扩展名
+var inputExts = []string{".csv", ".csv.gz", ".csv.enc", ".csv.gz.enc"}
+
+// ExpandInputs 将 --csv 参数展开为待导入的文件列表（按文件名排序）
+// 支持单个文件、通配符（如 dir/sales_2024-*.csv）以及目录（导入目录下所有 .csv 文件）
+func ExpandInputs(pattern string) ([]string, error) {
+	patterns := []string{pattern}
+	if info, err := os.Stat(pattern); err == nil {
+		if !info.IsDir() {
+			return []string{pattern}, nil
+		}
+		// 目录下的压缩和加密文件同样导入
+		patterns = patterns[:0]
+		for _, ext := range inputExts {
+			patterns = append(patterns, filepath.Join(pattern, "*"+ext))
 		}
 	}
-	// 开始事务批量插入
-	return batchInsert(db, insertSQL, reader, insertCols, cfg.Batch, cfg.SkipErrors, !cfg.Header, cfg.BinaryFormat)
+
+	var files []string
+	for _, p := range patterns {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, fmt.Errorf("无效的文件匹配模式 %s: %w", p, err)
+		}
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() {
+				files = append(files, m)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("没有匹配的CSV文件: %s", pattern)
+	}
+	sort.Strings(files)
+	return files, nil
 }
 
 // validateImportConfig 校验导入配置
@@ -106,13 +387,26 @@ func validateImportConfig(cfg config.ImportConfig) error {
 }
 
 type ColumnInfo struct {
-	Name     string
-	DataType string
-	Nullable bool
+	Name      string
+	DataType  string
+	Nullable  bool
+	Computed  bool   // 计算列或 rowversion 列，不能显式插入
+	MaxLength int    // 字符或二进制列的最大长度，-1 表示 max，0 表示不适用
+	Precision int    // 数值列的精度
+	Scale     int    // 数值列的小数位数
+	CodePage  int    // char/varchar 列排序规则的代码页，0 表示不适用
+	Collation string // 字符列的排序规则名，非字符列为空
+}
+
+// queryer 抽象 *sql.DB、*sql.Conn 与 *sql.Tx 共有的执行和查询方法
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
 // getTableColumns 从数据库获取表的列名
-func getTableColumns(db *sql.DB, tableName string) ([]ColumnInfo, error) {
+func getTableColumns(ctx context.Context, db queryer, tableName string) ([]ColumnInfo, error) {
 	// 转义表名
 	escapedTable, err := utils.EscapeQualifiedName(tableName)
 	if err != nil {
@@ -121,14 +415,17 @@ func getTableColumns(db *sql.DB, tableName string) ([]ColumnInfo, error) {
 
 	query := fmt.Sprintf(`
 		/* mssql_ie tool query for check column*/
-		SELECT COLUMN_NAME ,DATA_TYPE,IS_NULLABLE
+		SELECT COLUMN_NAME ,DATA_TYPE,IS_NULLABLE,
+			ISNULL(COLUMNPROPERTY(OBJECT_ID(QUOTENAME(TABLE_SCHEMA) + '.' + QUOTENAME(TABLE_NAME)), COLUMN_NAME, 'IsComputed'), 0),
+			ISNULL(CHARACTER_MAXIMUM_LENGTH, 0), ISNULL(NUMERIC_PRECISION, 0), ISNULL(NUMERIC_SCALE, 0),
+			ISNULL(CAST(COLLATIONPROPERTY(COLLATION_NAME, 'CodePage') AS INT), 0), ISNULL(COLLATION_NAME, '')
 		FROM INFORMATION_SCHEMA.COLUMNS 
 		WHERE TABLE_SCHEMA = COALESCE(PARSENAME('%s', 2), 'dbo')
 			AND TABLE_NAME = PARSENAME('%s', 1)
 		ORDER BY ORDINAL_POSITION
 	`, escapedTable, escapedTable)
 
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("查询表结构失败: %w", err)
 	}
@@ -138,10 +435,13 @@ func getTableColumns(db *sql.DB, tableName string) ([]ColumnInfo, error) {
 	for rows.Next() {
 		var col ColumnInfo
 		var nullableStr string
-		if err := rows.Scan(&col.Name, &col.DataType, &nullableStr); err != nil {
+		var isComputed int
+		if err := rows.Scan(&col.Name, &col.DataType, &nullableStr, &isComputed,
+			&col.MaxLength, &col.Precision, &col.Scale, &col.CodePage, &col.Collation); err != nil {
 			return nil, err
 		}
 		col.Nullable = nullableStr == "YES"
+		col.Computed = isComputed == 1 || strings.EqualFold(col.DataType, "timestamp")
 		columns = append(columns, col)
 	}
 
@@ -153,14 +453,14 @@ func getTableColumns(db *sql.DB, tableName string) ([]ColumnInfo, error) {
 }
 
 // truncateTable 清空表
-func truncateTable(db *sql.DB, tableName string) error {
+func truncateTable(ctx context.Context, db queryer, tableName string) error {
 	escapedTable, err := utils.EscapeQualifiedName(tableName)
 	if err != nil {
 		return fmt.Errorf("转义表名失败: %w", err)
 	}
 
-	// 使用TRUNCATE TABLE
-	_, err = db.Exec(fmt.Sprintf("TRUNCATE TABLE %s", escapedTable))
+	// 使用TRUNCATE TABLE（SQL Server中可随事务回滚）
+	_, err = db.ExecContext(ctx, fmt.Sprintf("TRUNCATE TABLE %s", escapedTable))
 	return err
 }
 
@@ -190,18 +490,45 @@ func buildInsertSQL(table string, safeCols []string) (string, error) {
 	), nil
 }
 
-// batchInsert 批量插入数据
-func batchInsert(db *sql.DB, insertSQL string, reader *csv.Reader, safeCols []ColumnInfo, batchSize int, skipErrors, skipFirstRow bool, binaryFormat string) error {
+// connLostError 连接中断时返回的错误，不再视为暂时性错误，使重试立即停止
+func connLostError(err error) error {
+	return fmt.Errorf("数据库连接已中断，无法在同一会话中重新执行当前批次（已提交的批次不受影响，请确认后重新导入剩余数据）: %v", err)
+}
+
+// batchInsert 批量插入数据，返回插入的行数
+// 普通模式下每 cfg.Batch 行提交一次事务；原子模式下使用调用方传入的 atomicTx，
+// 不分批提交也不回滚，由调用方统一提交或回滚
+// 普通模式下服务器返回暂时性错误（如死锁）时回滚当前批次，并在同一连接上按 cfg.Retry 重新执行该批次；
+// 连接中断时固定在该连接上的会话无法恢复（临时表和前置SQL的设置也随之丢失），不重试而是直接报错；
+// 提交失败同样只在服务器明确拒绝时重试，避免连接中断时重复插入已提交的数据
+func batchInsert(ctx context.Context, conn *sql.Conn, atomicTx *sql.Tx, plan *insertPlan, reader *csv.Reader, cfg config.ImportConfig) (int, error) {
+	insertSQL := plan.SQL
+	safeCols := plan.Columns
+	batchSize := cfg.Batch
+	skipErrors := cfg.SkipErrors
+	skipFirstRow := !cfg.Header
+	binaryFormat := cfg.BinaryFormat
+
 	// 开始事务
-	tx, err := db.Begin()
-	if err != nil {
-		return fmt.Errorf("开启事务失败: %w", err)
+	tx := atomicTx
+	if tx == nil {
+		var err error
+		tx, err = conn.BeginTx(ctx, nil)
+		if err != nil {
+			return 0, fmt.Errorf("开启事务失败: %w", err)
+		}
+	}
+	// rollback 仅回滚本函数开启的事务
+	rollback := func() {
+		if atomicTx == nil && tx != nil {
+			tx.Rollback()
+		}
 	}
 
 	// 异常回滚处理
 	defer func() {
 		if p := recover(); p != nil {
-			tx.Rollback()
+			rollback()
 			panic(p) // 重新抛出panic
 		}
 	}()
@@ -209,8 +536,8 @@ func batchInsert(db *sql.DB, insertSQL string, reader *csv.Reader, safeCols []Co
 	// 预处理插入语句
 	stmt, err := tx.Prepare(insertSQL)
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("预处理插入语句失败: %w", err)
+		rollback()
+		return 0, fmt.Errorf("预处理插入语句失败: %w", err)
 	}
 	defer stmt.Close()
 
@@ -219,6 +546,63 @@ func batchInsert(db *sql.DB, insertSQL string, reader *csv.Reader, safeCols []Co
 	rowNum := 0
 	errorRows := []int{}
 
+	// 当前批次已插入行的参数，用于遇到暂时性错误时重新执行整个批次
+	canRetry := atomicTx == nil && cfg.Retry.Retries > 0
+	// replayable 只有服务器返回的暂时性错误才能在同一连接上重新执行批次
+	replayable := func(err error) bool {
+		return canRetry && retry.IsServerError(err) && retry.IsTransient(err)
+	}
+	var pending [][]interface{}
+	// 新事务和语句都准备好后才替换 tx 和 stmt，重新开启失败时保留已回滚的旧事务，避免后续回滚时操作空指针
+	replay := func() error {
+		if stmt != nil {
+			stmt.Close()
+		}
+		if tx != nil {
+			tx.Rollback()
+		}
+		err := func() error {
+			newTx, err := conn.BeginTx(ctx, nil)
+			if err != nil {
+				return fmt.Errorf("重新开启事务失败: %w", err)
+			}
+			newStmt, err := newTx.Prepare(insertSQL)
+			if err != nil {
+				newTx.Rollback()
+				return fmt.Errorf("重新预处理语句失败: %w", err)
+			}
+			tx, stmt = newTx, newStmt
+			for _, args := range pending {
+				if _, err := stmt.Exec(args...); err != nil {
+					return err
+				}
+			}
+			return nil
+		}()
+		// 重试过程中连接中断时停止重试
+		if err != nil && !retry.IsServerError(err) {
+			return connLostError(err)
+		}
+		return err
+	}
+	// commit 提交当前批次，服务器明确拒绝且为暂时性错误时重新执行批次后再次提交
+	commit := func() error {
+		err := tx.Commit()
+		if err != nil && replayable(err) {
+			err = retry.Retry(ctx, cfg.Retry, "提交批次", err, func() error {
+				if err := replay(); err != nil {
+					return err
+				}
+				return tx.Commit()
+			})
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+		pending = pending[:0]
+		return err
+	}
+
 	// 循环读取CSV行
 	for {
 		row, err := reader.Read()
@@ -237,68 +621,111 @@ func batchInsert(db *sql.DB, insertSQL string, reader *csv.Reader, safeCols []Co
 				errorRows = append(errorRows, rowNum)
 				continue
 			}
-			tx.Rollback()
-			return fmt.Errorf("读取CSV行失败(行%d): %w", rowNum, err)
+			rollback()
+			return totalCount, fmt.Errorf("读取CSV行失败(行%d): %w", rowNum, err)
 		}
 
 		// 列数校验
-		if len(row) != len(safeCols) {
+		if len(row) != plan.Width {
 			if skipErrors {
 				errorRows = append(errorRows, rowNum)
 				continue
 			}
-			tx.Rollback()
-			return fmt.Errorf("行%d数据列数不匹配（期望%d列，实际%d列）", rowNum, len(safeCols), len(row))
+			rollback()
+			return totalCount, fmt.Errorf("行%d数据列数不匹配（期望%d列，实际%d列）", rowNum, plan.Width, len(row))
 		}
 
-		// 准备参数
-		args := make([]interface{}, len(row))
-		for i, v := range row {
+		// 准备参数，任一列转换失败或超长时整行按错误处理
+		args := make([]interface{}, len(safeCols), len(safeCols)+len(plan.ExtraArgs))
+		var rowErr error
+		for i, field := range plan.Fields {
+			v, err := plan.transform(i, row[field])
+			if err != nil {
+				rowErr = fmt.Errorf("行%d: %w", rowNum, err)
+				break
+			}
 			if v == "" {
 				args[i] = nil
-			} else {
-				args[i], err = convertValue(v, safeCols[i], binaryFormat)
-				if err != nil {
-					if skipErrors {
-						errorRows = append(errorRows, rowNum)
-						continue
+				continue
+			}
+			args[i], err = convertValue(v, safeCols[i], binaryFormat)
+			if err != nil {
+				rowErr = fmt.Errorf("转换值失败(行%d,列%d): %w", rowNum, field+1, err)
+				break
+			}
+			if args[i], err = fitValue(safeCols[i], v, args[i], cfg.OnOverflow, rowNum); err != nil {
+				rowErr = err
+				break
+			}
+		}
+		if rowErr != nil {
+			if cfg.OnOverflow == OverflowReject && isOverflow(rowErr) {
+				if plan.Rejects != nil {
+					if err := plan.Rejects.Write(row, rowErr); err != nil {
+						rollback()
+						return totalCount, fmt.Errorf("写入拒绝文件失败: %w", err)
 					}
-					tx.Rollback()
-					return fmt.Errorf("转换值失败(行%d,列%d): %w", rowNum, i+1, err)
 				}
+				continue
 			}
+			if skipErrors {
+				errorRows = append(errorRows, rowNum)
+				continue
+			}
+			rollback()
+			return totalCount, rowErr
 		}
+		args = append(args, plan.ExtraArgs...)
 
-		// 执行插入
+		// 执行插入，暂时性错误时重新执行整个批次（含当前行）
 		if _, err := stmt.Exec(args...); err != nil {
-			if skipErrors {
+			if replayable(err) {
+				pending = append(pending, args)
+				if err := retry.Retry(ctx, cfg.Retry, fmt.Sprintf("插入批次(行%d)", rowNum), err, replay); err != nil {
+					rollback()
+					return totalCount, fmt.Errorf("插入行失败(行%d): %w", rowNum, err)
+				}
+			} else if canRetry && retry.IsTransient(err) {
+				rollback()
+				return totalCount, fmt.Errorf("插入行失败(行%d): %w", rowNum, connLostError(err))
+			} else if skipErrors {
 				errorRows = append(errorRows, rowNum)
 				continue
+			} else {
+				rollback()
+				return totalCount, fmt.Errorf("插入行失败(行%d): %w", rowNum, err)
 			}
-			tx.Rollback()
-			return fmt.Errorf("插入行失败(行%d): %w", rowNum, err)
+		} else if canRetry {
+			pending = append(pending, args)
 		}
 
 		batchCount++
 		totalCount++
 
+		// 原子模式下不分批提交，仅输出进度
+		if atomicTx != nil && batchCount >= batchSize {
+			batchCount = 0
+			fmt.Printf("已导入 %d 行（未提交）...\n", totalCount)
+			continue
+		}
+
 		// 达到批量大小提交事务
 		if batchCount >= batchSize {
-			if err := tx.Commit(); err != nil {
-				return fmt.Errorf("提交批量事务失败(累计%d行): %w", totalCount, err)
+			if err := commit(); err != nil {
+				return totalCount, fmt.Errorf("提交批量事务失败(累计%d行): %w", totalCount, err)
 			}
 
 			// 开始新事务
-			tx, err = db.Begin()
+			tx, err = conn.BeginTx(ctx, nil)
 			if err != nil {
-				return fmt.Errorf("重新开启事务失败: %w", err)
+				return totalCount, fmt.Errorf("重新开启事务失败: %w", err)
 			}
 
 			// 重新预处理语句
 			stmt, err = tx.Prepare(insertSQL)
 			if err != nil {
 				tx.Rollback()
-				return fmt.Errorf("重新预处理语句失败: %w", err)
+				return totalCount, fmt.Errorf("重新预处理语句失败: %w", err)
 			}
 			batchCount = 0
 
@@ -306,10 +733,14 @@ func batchInsert(db *sql.DB, insertSQL string, reader *csv.Reader, safeCols []Co
 		}
 	}
 
-	// 提交剩余数据
-	if batchCount > 0 {
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("提交剩余数据失败: %w", err)
+	// 提交剩余数据（原子模式下由调用方提交）
+	if atomicTx == nil {
+		if batchCount > 0 {
+			if err := commit(); err != nil {
+				return totalCount, fmt.Errorf("提交剩余数据失败: %w", err)
+			}
+		} else {
+			tx.Rollback()
 		}
 	}
 
@@ -319,7 +750,7 @@ func batchInsert(db *sql.DB, insertSQL string, reader *csv.Reader, safeCols []Co
 		fmt.Printf("⚠️  跳过 %d 行错误数据: %v\n", len(errorRows), errorRows)
 	}
 
-	return nil
+	return totalCount, nil
 }
 
 func convertValue(value string, col ColumnInfo, binaryFormat string) (interface{}, error) {
@@ -0,0 +1,136 @@
+// importer/importer_test.go
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+
+	"github.com/mssql_ie/config"
+)
+
+// fakeDB 模拟数据库连接，按调用次数注入插入和开启事务的错误
+type fakeDB struct {
+	execErrs  []error // 第 n 次插入返回的错误，超出长度时成功
+	beginErrs []error // 第 n 次开启事务返回的错误，超出长度时成功
+	execs     int
+	begins    int
+	commits   int
+}
+
+func (d *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: d}, nil }
+func (d *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return &fakeStmt{db: c.db}, nil }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.begins++
+	if n := c.db.begins - 1; n < len(c.db.beginErrs) && c.db.beginErrs[n] != nil {
+		return nil, c.db.beginErrs[n]
+	}
+	return &fakeTx{db: c.db}, nil
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (t *fakeTx) Commit() error   { t.db.commits++; return nil }
+func (t *fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct{ db *fakeDB }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.db.execs++
+	if n := s.db.execs - 1; n < len(s.db.execErrs) && s.db.execErrs[n] != nil {
+		return nil, s.db.execErrs[n]
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("不支持查询")
+}
+
+// runBatchInsert 在模拟连接上导入 data，返回插入的行数
+func runBatchInsert(t *testing.T, db *fakeDB, data string, cfg config.ImportConfig) (int, error) {
+	t.Helper()
+	sqlDB := sql.OpenDB(db)
+	t.Cleanup(func() { sqlDB.Close() })
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		t.Fatalf("获取连接失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	plan := &insertPlan{
+		SQL:     "INSERT INTO [dbo].[t] ([id]) VALUES (?)",
+		Columns: []ColumnInfo{{Name: "id", DataType: "int", Nullable: true}},
+		Fields:  []int{0},
+		Width:   1,
+	}
+	return batchInsert(ctx, conn, nil, plan, csv.NewReader(strings.NewReader(data)), cfg)
+}
+
+func TestBatchInsertReplay(t *testing.T) {
+	deadlock := mssql.Error{Number: 1205, Message: "deadlock victim"}
+	retryCfg := config.RetryConfig{Retries: 2, Backoff: time.Millisecond}
+
+	tests := []struct {
+		name      string
+		db        *fakeDB
+		want      int
+		wantErr   string
+		wantExecs int
+	}{
+		{
+			name:      "死锁后重新执行批次",
+			db:        &fakeDB{execErrs: []error{nil, deadlock}},
+			want:      3,
+			wantExecs: 5, // 第2行失败后重新执行前2行，再插入第3行
+		},
+		{
+			name:      "重新开启事务失败",
+			db:        &fakeDB{execErrs: []error{nil, deadlock}, beginErrs: []error{nil, io.ErrUnexpectedEOF}},
+			want:      1,
+			wantErr:   "数据库连接已中断",
+			wantExecs: 2,
+		},
+		{
+			name:      "重新开启事务遇到死锁后再次重试",
+			db:        &fakeDB{execErrs: []error{nil, deadlock}, beginErrs: []error{nil, deadlock}},
+			want:      3,
+			wantExecs: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 调用方已读取标题行
+			cfg := config.ImportConfig{Batch: 10, Header: true, Retry: retryCfg}
+			got, err := runBatchInsert(t, tt.db, "1\n2\n3\n", cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误 = %v，期望包含 %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("意外错误: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("插入行数 = %d，期望 %d", got, tt.want)
+			}
+			if tt.db.execs != tt.wantExecs {
+				t.Errorf("执行次数 = %d，期望 %d", tt.db.execs, tt.wantExecs)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// importer/overflow.go
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// 超长值处理策略
+const (
+	OverflowError    = "error"    // 报告具体的行和列并终止导入
+	OverflowTruncate = "truncate" // 截断字符串和二进制值并输出警告
+	OverflowReject   = "reject"   // 将整行写入拒绝文件后继续导入
+)
+
+// overflowError 值超出列的长度或精度限制
+type overflowError struct {
+	Row    int
+	Column string
+	Err    error
+}
+
+func (e *overflowError) Error() string {
+	return fmt.Sprintf("行%d列%s的值超出限制: %v", e.Row, e.Column, e.Err)
+}
+
+func (e *overflowError) Unwrap() error {
+	return e.Err
+}
+
+// fitValue 按超长值处理策略检查并处理转换后的值
+// truncate 策略下只截断字符串和二进制列的值；定点数等其他类型无法截断，按错误处理
+func fitValue(col ColumnInfo, value string, converted interface{}, policy string, rowNum int) (interface{}, error) {
+	err := checkLength(col, value, converted)
+	if err == nil {
+		return converted, nil
+	}
+	if policy == OverflowTruncate {
+		switch v := converted.(type) {
+		case string:
+			switch strings.ToLower(col.DataType) {
+			case "char", "varchar":
+				fmt.Printf("⚠️  行%d列%s的值长度超过 %d 字节，已截断\n", rowNum, col.Name, col.MaxLength)
+				return truncateEncoded(col, v, col.MaxLength), nil
+			case "nchar", "nvarchar":
+				fmt.Printf("⚠️  行%d列%s的值长度超过 %d，已截断\n", rowNum, col.Name, col.MaxLength)
+				return truncateUTF16(v, col.MaxLength), nil
+			}
+		case []byte:
+			switch strings.ToLower(col.DataType) {
+			case "binary", "varbinary":
+				fmt.Printf("⚠️  行%d列%s的值长度超过 %d 字节，已截断\n", rowNum, col.Name, col.MaxLength)
+				return v[:col.MaxLength], nil
+			}
+		}
+	}
+	return nil, &overflowError{Row: rowNum, Column: col.Name, Err: err}
+}
+
+// truncateBytes 按 UTF-8 字节数截断字符串（UTF-8 排序规则），不截断多字节字符
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// truncateUTF16 按 UTF-16 码元数截断字符串（nchar/nvarchar 的长度单位），不截断代理对
+func truncateUTF16(s string, n int) string {
+	units := 0
+	for i, r := range s {
+		size := utf16.RuneLen(r)
+		if size < 0 {
+			size = 1
+		}
+		if units+size > n {
+			return s[:i]
+		}
+		units += size
+	}
+	return s
+}
+
+// isOverflow 判断错误是否为超长值错误
+func isOverflow(err error) bool {
+	var oe *overflowError
+	return errors.As(err, &oe)
+}
+
+// rejectWriter 将被拒绝的行写入CSV文件，末尾追加 _error 列说明原因
+type rejectWriter struct {
+	path   string
+	file   *os.File
+	writer *csv.Writer
+	header bool // 是否已写入标题行
+	count  int
+}
+
+// newRejectWriter 创建拒绝文件，path 为空时返回 nil
+func newRejectWriter(path string, delimiter rune) (*rejectWriter, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建拒绝文件失败: %w", err)
+	}
+	writer := csv.NewWriter(file)
+	writer.Comma = delimiter
+	return &rejectWriter{path: path, file: file, writer: writer}, nil
+}
+
+// WriteHeader 写入标题行，仅在尚未写入任何内容时生效
+func (w *rejectWriter) WriteHeader(header []string) error {
+	if w.header || w.count > 0 || len(header) == 0 {
+		return nil
+	}
+	w.header = true
+	return w.writer.Write(append(append([]string{}, header...), "_error"))
+}
+
+// Write 写入被拒绝的行
+func (w *rejectWriter) Write(row []string, reason error) error {
+	w.count++
+	return w.writer.Write(append(append([]string{}, row...), strings.ReplaceAll(reason.Error(), "\n", " ")))
+}
+
+// Close 刷新并关闭拒绝文件
+func (w *rejectWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("写入拒绝文件失败: %w", err)
+	}
+	if w.count > 0 {
+		fmt.Printf("⚠️  %d 行被拒绝，已写入: %s\n", w.count, w.path)
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,148 @@
+// importer/reconcile.go
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/utils"
+)
+
+// reconcileTable 计算文件校验和时用于暂存文件数据的临时表
+const reconcileTable = "#mssql_ie_reconcile"
+
+// reconciler 导入后核对表中的数据与文件是否一致
+// 行数核对比较插入行数与 COUNT_BIG(*) 的增量；校验和核对将文件再次加载到临时表，
+// 比较与行顺序无关的 SUM(BINARY_CHECKSUM(...)) 在表中的增量与临时表中的值
+type reconciler struct {
+	table       string   // 转义后的表名
+	columns     []string // 参与校验和的列（已转义）
+	checksum    bool
+	beforeCount int64
+	beforeSum   int64
+}
+
+// newReconciler 记录导入前表的行数（以及校验和）
+func newReconciler(ctx context.Context, db queryer, cfg config.ImportConfig, columnInfos []ColumnInfo) (*reconciler, error) {
+	table, err := utils.EscapeQualifiedName(cfg.Table)
+	if err != nil {
+		return nil, fmt.Errorf("转义表名失败: %w", err)
+	}
+	r := &reconciler{table: table, checksum: cfg.ReconcileChecksum}
+	if r.checksum {
+		for _, col := range columnInfos {
+			if col.Computed {
+				continue
+			}
+			// BINARY_CHECKSUM 不支持大对象和空间类型
+			switch strings.ToLower(col.DataType) {
+			case "text", "ntext", "image", "xml", "geometry", "geography", "sql_variant":
+				continue
+			}
+			r.columns = append(r.columns, utils.EscapeIdentifier(col.Name))
+		}
+		if len(r.columns) == 0 {
+			return nil, fmt.Errorf("表 %s 没有可计算校验和的列", cfg.Table)
+		}
+	}
+
+	if r.beforeCount, r.beforeSum, err = r.measure(ctx, db, r.table); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// measure 统计表的行数和校验和
+func (r *reconciler) measure(ctx context.Context, db queryer, table string) (int64, int64, error) {
+	sumExpr := "0"
+	if r.checksum {
+		sumExpr = fmt.Sprintf("ISNULL(SUM(CAST(BINARY_CHECKSUM(%s) AS BIGINT)), 0)", strings.Join(r.columns, ", "))
+	}
+	query := fmt.Sprintf(`
+		/* mssql_ie tool query for reconcile*/
+		SELECT COUNT_BIG(*), %s FROM %s`, sumExpr, table)
+
+	var count, sum int64
+	if err := db.QueryRowContext(ctx, query).Scan(&count, &sum); err != nil {
+		return 0, 0, fmt.Errorf("统计表 %s 的行数和校验和失败: %w", table, err)
+	}
+	return count, sum, nil
+}
+
+// verify 导入完成后核对数据，不一致时返回包含核对报告的错误
+// 原子模式下在导入事务内执行，核对失败时整个导入回滚
+func (r *reconciler) verify(ctx context.Context, conn *sql.Conn, atomicTx *sql.Tx, cfg config.ImportConfig, columnInfos []ColumnInfo, files []string, inserted int) error {
+	var db queryer = conn
+	if atomicTx != nil {
+		db = atomicTx
+	}
+
+	afterCount, afterSum, err := r.measure(ctx, db, r.table)
+	if err != nil {
+		return err
+	}
+	countDelta := afterCount - r.beforeCount
+
+	var fileSum int64
+	if r.checksum {
+		if fileSum, err = r.fileChecksum(ctx, conn, atomicTx, db, cfg, columnInfos, files); err != nil {
+			return err
+		}
+	}
+	sumDelta := afterSum - r.beforeSum
+
+	fmt.Println("数据核对:")
+	fmt.Printf("   插入行数: %d，表行数增量: %d（导入前 %d，导入后 %d）\n", inserted, countDelta, r.beforeCount, afterCount)
+	if r.checksum {
+		fmt.Printf("   文件校验和: %d，表校验和增量: %d\n", fileSum, sumDelta)
+	}
+
+	var problems []string
+	if countDelta != int64(inserted) {
+		problems = append(problems, fmt.Sprintf("表行数增量 %d 与插入行数 %d 不一致（可能存在触发器、忽略的重复键或并发写入）", countDelta, inserted))
+	}
+	if r.checksum && sumDelta != fileSum {
+		problems = append(problems, "表中新增数据的校验和与文件不一致")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("数据核对失败: %s", strings.Join(problems, "；"))
+	}
+	fmt.Println("✅ 数据核对通过")
+	return nil
+}
+
+// fileChecksum 将文件再次加载到临时表并计算校验和
+// 加载过程与正式导入相同（值转换、超长值处理等），因此两侧的值一致
+func (r *reconciler) fileChecksum(ctx context.Context, conn *sql.Conn, atomicTx *sql.Tx, db queryer, cfg config.ImportConfig, columnInfos []ColumnInfo, files []string) (int64, error) {
+	var cols []string
+	for _, col := range columnInfos {
+		if !col.Computed {
+			cols = append(cols, utils.EscapeIdentifier(col.Name))
+		}
+	}
+	colList := strings.Join(cols, ", ")
+	// UNION ALL 使 SELECT INTO 生成的临时表不带自增属性和约束
+	create := fmt.Sprintf("SELECT TOP 0 %s INTO %s FROM %s UNION ALL SELECT TOP 0 %s FROM %s",
+		colList, reconcileTable, r.table, colList, r.table)
+	if _, err := db.ExecContext(ctx, create); err != nil {
+		return 0, fmt.Errorf("创建临时表失败: %w", err)
+	}
+	defer db.ExecContext(ctx, "DROP TABLE "+reconcileTable)
+
+	stageCfg := cfg
+	stageCfg.Table = reconcileTable
+	stageCfg.Truncate = false
+	stageCfg.RejectPath = ""
+	fmt.Println("正在将文件加载到临时表以计算校验和...")
+	for _, path := range files {
+		if _, err := loadFile(ctx, conn, atomicTx, nil, stageCfg, columnInfos, path); err != nil {
+			return 0, fmt.Errorf("加载文件 %s 到临时表失败: %w", path, err)
+		}
+	}
+
+	_, sum, err := r.measure(ctx, db, reconcileTable)
+	return sum, err
+}
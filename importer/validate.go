@@ -0,0 +1,398 @@
+// importer/validate.go
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/fileio"
+	"github.com/mssql_ie/schema"
+	"github.com/mssql_ie/utils"
+)
+
+// 报告中最多在控制台输出的问题数
+const maxPrintedIssues = 20
+
+// Issue 校验发现的问题
+type Issue struct {
+	File    string
+	Row     int
+	Column  string
+	Value   string
+	Message string
+}
+
+// uniqueKey 表上的主键或唯一键，键值跨文件累计
+type uniqueKey struct {
+	Name    string
+	Columns []string
+	seen    map[string]int // 键值 -> 首次出现的行号
+	files   map[string]string
+}
+
+// fields 返回键列在插入计划中的位置（plan.Columns 的下标）；键列不全在文件中时返回 false
+// 各文件的标题行顺序可能不同，因此每个文件单独确定位置
+func (k *uniqueKey) fields(plan *insertPlan) ([]int, bool) {
+	fields := make([]int, len(k.Columns))
+	for i, name := range k.Columns {
+		pos := -1
+		for j, col := range plan.Columns {
+			if strings.EqualFold(col.Name, name) {
+				pos = j
+				break
+			}
+		}
+		if pos < 0 {
+			return nil, false
+		}
+		fields[i] = pos
+	}
+	return fields, true
+}
+
+// keyValue 返回值在键比较中的规范形式，使导入后在数据库中相等的值得到相同的结果
+// value 为转换后的文本（为空表示 NULL，SQL Server 的唯一键中 NULL 之间视为重复），converted 为 convertValue 的结果
+func keyValue(col ColumnInfo, value string, converted interface{}) string {
+	if value == "" {
+		return "\x00"
+	}
+	if b, ok := converted.([]byte); ok {
+		return hex.EncodeToString(b)
+	}
+	if b, ok := converted.(bool); ok {
+		if b {
+			return "1"
+		}
+		return "0"
+	}
+	trimmed := strings.TrimSpace(value)
+	switch strings.ToLower(col.DataType) {
+	case "tinyint", "smallint", "int", "bigint":
+		if n, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+			return strconv.FormatInt(n, 10)
+		}
+	case "decimal", "numeric", "money", "smallmoney":
+		if r, ok := new(big.Rat).SetString(trimmed); ok {
+			return r.RatString()
+		}
+	case "float", "real":
+		if f, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+	case "uniqueidentifier":
+		return strings.ToUpper(trimmed)
+	case "char", "varchar", "nchar", "nvarchar":
+		// 字符串比较忽略尾随空格，并按列的排序规则忽略大小写或重音
+		return collationKey(col.Collation, strings.TrimRight(value, " "))
+	}
+	return value
+}
+
+// validateFiles 校验模式：完整解析并转换每一行，不执行任何写操作
+func validateFiles(ctx context.Context, conn *sql.Conn, cfg config.ImportConfig, files []string) error {
+	columnInfos, err := getTableColumns(ctx, conn, cfg.Table)
+	if err != nil {
+		return fmt.Errorf("获取表列名失败: %w", err)
+	}
+
+	// 严格模式下读取主键和唯一键，用于检查文件内的重复键
+	var keys []*uniqueKey
+	if cfg.Strict {
+		schemaName, tableName, err := utils.SplitQualifiedName(cfg.Table)
+		if err != nil {
+			return fmt.Errorf("无效的表名格式: %w", err)
+		}
+		def, err := schema.LoadTable(ctx, conn, schemaName, tableName)
+		if err != nil {
+			return fmt.Errorf("获取表结构失败: %w", err)
+		}
+		var keyDefs []schema.Index
+		if def.PrimaryKey != nil {
+			keyDefs = append(keyDefs, *def.PrimaryKey)
+		}
+		for _, idx := range def.Indexes {
+			// 筛选索引只约束部分行，无法在客户端准确判断
+			if idx.Unique && idx.Filter == "" {
+				keyDefs = append(keyDefs, idx)
+			}
+		}
+		for _, def := range keyDefs {
+			key := &uniqueKey{Name: def.Name, seen: map[string]int{}, files: map[string]string{}}
+			for _, kc := range def.Columns {
+				key.Columns = append(key.Columns, kc.Name)
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	var issues []Issue
+	total := 0
+	for i, path := range files {
+		if len(files) > 1 {
+			fmt.Printf("[%d/%d] 正在校验文件 %s\n", i+1, len(files), path)
+		}
+		count, fileIssues, err := validateFile(cfg, columnInfos, keys, path)
+		if err != nil {
+			return fmt.Errorf("校验文件 %s 失败: %w", path, err)
+		}
+		total += count
+		issues = append(issues, fileIssues...)
+	}
+
+	if cfg.ReportPath != "" {
+		if err := writeReport(cfg.ReportPath, issues); err != nil {
+			return err
+		}
+	}
+
+	for i, issue := range issues {
+		if i >= maxPrintedIssues {
+			fmt.Printf("   ... 其余 %d 个问题请查看校验报告\n", len(issues)-maxPrintedIssues)
+			break
+		}
+		fmt.Printf("   %s 行%d 列%s: %s\n", issue.File, issue.Row, issue.Column, issue.Message)
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("校验未通过: 共 %d 行数据，发现 %d 个问题", total, len(issues))
+	}
+	fmt.Printf("✅ 校验通过: 共 %d 行数据，未发现问题（未写入任何数据）\n", total)
+	return nil
+}
+
+// validateFile 校验单个文件，返回数据行数和发现的问题
+// 键值在 keys 中跨文件累计，多个文件导入同一张表时同样能发现重复
+func validateFile(cfg config.ImportConfig, columnInfos []ColumnInfo, keys []*uniqueKey, path string) (int, []Issue, error) {
+	file, err := fileio.Open(path, cfg.Passphrase)
+	if err != nil {
+		return 0, nil, fmt.Errorf("打开CSV文件失败: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(utils.GetTransformersRead(file, cfg.FileCharset))
+	reader.Comma = cfg.Delimiter
+
+	plan, err := buildInsertPlan(reader, columnInfos, path, cfg)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// 按本文件的标题行确定键列位置；键列不全在文件中的键无法校验
+	var fileKeys []*uniqueKey
+	var keyFields [][]int
+	for _, key := range keys {
+		if fields, ok := key.fields(plan); ok {
+			fileKeys = append(fileKeys, key)
+			keyFields = append(keyFields, fields)
+		}
+	}
+
+	var issues []Issue
+	add := func(row int, column, value, message string) {
+		issues = append(issues, Issue{File: path, Row: row, Column: column, Value: value, Message: message})
+	}
+
+	rowNum := 0
+	if cfg.Header {
+		rowNum = 1
+	}
+	count := 0
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		rowNum++
+		if err != nil {
+			add(rowNum, "", "", fmt.Sprintf("读取CSV行失败: %v", err))
+			continue
+		}
+		count++
+
+		if len(row) != plan.Width {
+			add(rowNum, "", "", fmt.Sprintf("数据列数不匹配（期望%d列，实际%d列）", plan.Width, len(row)))
+			continue
+		}
+
+		// keyValues 各列转换后用于键比较的值，转换失败的列为 nil
+		keyValues := make([]*string, len(plan.Fields))
+		for i, field := range plan.Fields {
+			col := plan.Columns[i]
+			v, err := plan.transform(i, row[field])
+			if err != nil {
+				add(rowNum, col.Name, row[field], err.Error())
+				continue
+			}
+			if v == "" {
+				// 导入时空值以 NULL 插入，非空列必然插入失败
+				if !col.Nullable {
+					add(rowNum, col.Name, v, "非空列的值为空，导入时将插入 NULL 而失败")
+					continue
+				}
+				kv := keyValue(col, v, nil)
+				keyValues[i] = &kv
+				continue
+			}
+			converted, err := convertValue(v, col, cfg.BinaryFormat)
+			if err != nil {
+				add(rowNum, col.Name, v, fmt.Sprintf("转换值失败: %v", err))
+				continue
+			}
+			if cfg.Strict {
+				if err := checkColumnValue(col, v, converted); err != nil {
+					add(rowNum, col.Name, v, err.Error())
+				}
+			}
+			kv := keyValue(col, v, converted)
+			keyValues[i] = &kv
+		}
+
+	keys:
+		for i, key := range fileKeys {
+			values := make([]string, len(keyFields[i]))
+			for j, pos := range keyFields[i] {
+				// 键列的值无法导入时已报告问题，不再检查重复
+				if keyValues[pos] == nil {
+					continue keys
+				}
+				values[j] = *keyValues[pos]
+			}
+			k := strings.Join(values, "\x1f")
+			if first, ok := key.seen[k]; ok {
+				add(rowNum, key.Name, strings.ReplaceAll(strings.Join(values, ","), "\x00", "NULL"),
+					fmt.Sprintf("键值与 %s 行%d 重复", key.files[k], first))
+				continue
+			}
+			key.seen[k] = rowNum
+			key.files[k] = path
+		}
+	}
+
+	return count, issues, nil
+}
+
+// checkColumnValue 检查值是否超出列的长度、范围或精度限制
+// value 为CSV中的原始值，converted 为 convertValue 转换后的值
+func checkColumnValue(col ColumnInfo, value string, converted interface{}) error {
+	if err := checkLength(col, value, converted); err != nil {
+		return err
+	}
+	switch strings.ToLower(col.DataType) {
+	case "tinyint":
+		if n, err := strconv.ParseInt(value, 10, 64); err != nil || n < 0 || n > 255 {
+			return fmt.Errorf("不是有效的 tinyint 值（0-255）")
+		}
+	case "smallint":
+		if _, err := strconv.ParseInt(value, 10, 16); err != nil {
+			return fmt.Errorf("不是有效的 smallint 值")
+		}
+	case "int":
+		if _, err := strconv.ParseInt(value, 10, 32); err != nil {
+			return fmt.Errorf("不是有效的 int 值")
+		}
+	case "bigint":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("不是有效的 bigint 值")
+		}
+	case "decimal", "numeric":
+		if _, ok := new(big.Rat).SetString(strings.TrimSpace(value)); !ok {
+			return fmt.Errorf("不是有效的数值")
+		}
+	}
+	return nil
+}
+
+// checkLength 检查字符串、二进制值的长度以及定点数的整数位数是否超出列定义
+// 无法解析的数值不在此处报错，由数据库按原有方式处理
+func checkLength(col ColumnInfo, value string, converted interface{}) error {
+	switch strings.ToLower(col.DataType) {
+	case "char", "varchar":
+		// char/varchar 的长度以列排序规则代码页中的字节数计。支持的代码页中每个字符的字节数
+		// 都不超过其 UTF-8 字节数，因此 UTF-8 长度未超出时无需编码
+		if col.MaxLength <= 0 || len(value) <= col.MaxLength {
+			return nil
+		}
+		if n, ok := encodedLen(col, value); ok && n > col.MaxLength {
+			return fmt.Errorf("长度 %d 字节超过列的最大长度 %d 字节", n, col.MaxLength)
+		}
+	case "nchar", "nvarchar":
+		// nchar/nvarchar 的长度以 UTF-16 码元计，增补字符占两个码元
+		if n := utf16Len(value); col.MaxLength > 0 && n > col.MaxLength {
+			return fmt.Errorf("长度 %d 超过列的最大长度 %d", n, col.MaxLength)
+		}
+	case "binary", "varbinary":
+		if b, ok := converted.([]byte); ok && col.MaxLength > 0 && len(b) > col.MaxLength {
+			return fmt.Errorf("长度 %d 字节超过列的最大长度 %d 字节", len(b), col.MaxLength)
+		}
+	case "decimal", "numeric":
+		return checkDecimal(value, col.Precision, col.Scale)
+	}
+	return nil
+}
+
+// utf16Len 返回字符串的 UTF-16 码元数
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if utf16.RuneLen(r) == 2 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// checkDecimal 检查定点数的整数位数是否超过 precision-scale
+// 小数位数超过 scale 时 SQL Server 会自动舍入，不视为错误
+func checkDecimal(value string, precision, scale int) error {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return nil
+	}
+	intPart := new(big.Int).Quo(r.Num(), r.Denom())
+	digits := len(intPart.Abs(intPart).String())
+	if intPart.Sign() == 0 {
+		digits = 0
+	}
+	if precision > 0 && digits > precision-scale {
+		return fmt.Errorf("整数部分 %d 位超过 decimal(%d,%d) 允许的 %d 位", digits, precision, scale, precision-scale)
+	}
+	return nil
+}
+
+// writeReport 将校验发现的问题写入CSV报告
+func writeReport(path string, issues []Issue) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建校验报告失败: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"file", "row", "column", "value", "issue"}); err != nil {
+		return fmt.Errorf("写入校验报告失败: %w", err)
+	}
+	for _, issue := range issues {
+		record := []string{issue.File, strconv.Itoa(issue.Row), issue.Column, issue.Value, issue.Message}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("写入校验报告失败: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("写入校验报告失败: %w", err)
+	}
+	fmt.Printf("校验报告已写入: %s\n", path)
+	return nil
+}
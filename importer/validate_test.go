@@ -0,0 +1,111 @@
+// importer/validate_test.go
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mssql_ie/config"
+	"github.com/mssql_ie/transforms"
+)
+
+// writeCSV 在临时目录中写入CSV文件并返回路径
+func writeCSV(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("写入CSV文件失败: %v", err)
+	}
+	return path
+}
+
+func TestValidateFileDuplicateKeys(t *testing.T) {
+	codeSet, err := transforms.Load([]string{"code=trim|upper"}, "")
+	if err != nil {
+		t.Fatalf("解析列值转换失败: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		columns    []ColumnInfo
+		transforms transforms.Set
+		data       string
+		wantDup    int
+	}{
+		{
+			name:       "转换后相同的字符串键",
+			columns:    []ColumnInfo{{Name: "code", DataType: "varchar", MaxLength: 10}},
+			transforms: codeSet,
+			data:       "code\n a\nA\n",
+			wantDup:    1,
+		},
+		{
+			name:    "前导零的整数键",
+			columns: []ColumnInfo{{Name: "id", DataType: "int"}},
+			data:    "id\n1\n01\n2\n",
+			wantDup: 1,
+		},
+		{
+			name:    "不区分大小写排序规则的字符串键",
+			columns: []ColumnInfo{{Name: "code", DataType: "nvarchar", MaxLength: 10, Collation: "SQL_Latin1_General_CP1_CI_AS"}},
+			data:    "code\nab\nab  \nAB\n",
+			wantDup: 2,
+		},
+		{
+			name:    "区分大小写排序规则的字符串键",
+			columns: []ColumnInfo{{Name: "code", DataType: "nvarchar", MaxLength: 10, Collation: "Latin1_General_CS_AS"}},
+			data:    "code\nab\nab  \nAB\n",
+			wantDup: 1,
+		},
+		{
+			name:    "不区分重音排序规则的字符串键",
+			columns: []ColumnInfo{{Name: "code", DataType: "varchar", MaxLength: 10, Collation: "Latin1_General_CI_AI"}},
+			data:    "code\ncafé\nCAFE\ncafe\n",
+			wantDup: 2,
+		},
+		{
+			name:    "二进制排序规则的字符串键",
+			columns: []ColumnInfo{{Name: "code", DataType: "varchar", MaxLength: 10, Collation: "Latin1_General_BIN2"}},
+			data:    "code\ncafé\nCAFE\ncafe\n",
+			wantDup: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.ImportConfig{Table: "dbo.t", Header: true, Delimiter: ',', Strict: true, Transforms: tt.transforms}
+			keys := []*uniqueKey{{
+				Name:    "PK_t",
+				Columns: []string{tt.columns[0].Name},
+				seen:    map[string]int{},
+				files:   map[string]string{},
+			}}
+			_, issues, err := validateFile(cfg, tt.columns, keys, writeCSV(t, tt.data))
+			if err != nil {
+				t.Fatalf("校验失败: %v", err)
+			}
+			dup := 0
+			for _, issue := range issues {
+				if strings.Contains(issue.Message, "重复") {
+					dup++
+				}
+			}
+			if dup != tt.wantDup {
+				t.Errorf("重复键问题 %d 个，期望 %d 个: %+v", dup, tt.wantDup, issues)
+			}
+		})
+	}
+}
+
+func TestValidateFileEmptyNotNull(t *testing.T) {
+	columns := []ColumnInfo{{Name: "id", DataType: "int"}, {Name: "note", DataType: "varchar", MaxLength: 10, Nullable: true}}
+	cfg := config.ImportConfig{Table: "dbo.t", Header: true, Delimiter: ','}
+	_, issues, err := validateFile(cfg, columns, nil, writeCSV(t, "id,note\n,x\n1,\n"))
+	if err != nil {
+		t.Fatalf("校验失败: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Row != 2 || issues[0].Column != "id" {
+		t.Fatalf("问题 = %+v，期望只报告第2行 id 列的空值", issues)
+	}
+}
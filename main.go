@@ -4,12 +4,26 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/mssql_ie/config"
 	"github.com/mssql_ie/conn"
+	"github.com/mssql_ie/copier"
+	"github.com/mssql_ie/differ"
+	"github.com/mssql_ie/dump"
 	"github.com/mssql_ie/exporter"
+	"github.com/mssql_ie/hooks"
 	"github.com/mssql_ie/importer"
+	"github.com/mssql_ie/masking"
+	"github.com/mssql_ie/profile"
+	"github.com/mssql_ie/schema"
+	"github.com/mssql_ie/sqlparams"
+	"github.com/mssql_ie/sqlscript"
+	"github.com/mssql_ie/transforms"
+	"github.com/mssql_ie/utils"
 	"github.com/urfave/cli/v2"
 )
 
@@ -26,64 +40,9 @@ func main() {
 		Usage:    "SQL Server 数据导入导出工具",
 		Suggest:  true,
 		HideHelp: false,
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:    "server",
-				Aliases: []string{"S"},
-				Value:   "localhost",
-				Usage:   "SQL Server地址",
-				EnvVars: []string{"MSSQL_SERVER", "DB_SERVER"},
-			},
-			&cli.IntFlag{
-				Name:    "port",
-				Aliases: []string{"P"},
-				Value:   1433,
-				Usage:   "SQL Server端口",
-				EnvVars: []string{"MSSQL_PORT", "DB_PORT"},
-			},
-			&cli.StringFlag{
-				Name:    "user",
-				Aliases: []string{"U"},
-				Value:   "sa",
-				Usage:   "数据库用户名",
-				EnvVars: []string{"MSSQL_USER", "DB_USER"},
-			},
-			&cli.StringFlag{
-				Name:     "password",
-				Aliases:  []string{"W"},
-				Usage:    "数据库密码",
-				Required: true,
-				EnvVars:  []string{"MSSQL_PASSWORD", "DB_PASSWORD"},
-			},
-			&cli.StringFlag{
-				Name:     "db",
-				Aliases:  []string{"D"},
-				Usage:    "数据库名",
-				Required: true,
-				EnvVars:  []string{"MSSQL_DBNAME", "DB_NAME"},
-			},
-			&cli.StringFlag{
-				Name:    "encrypt",
-				Aliases: []string{"E"},
-				Value:   "off",
-				Usage:   "是否启用加密连接",
-				EnvVars: []string{"MSSQL_ENCRYPT"},
-			},
-			&cli.StringFlag{
-				Name:    "charset",
-				Aliases: []string{"C"},
-				Usage:   "字符集 (例如: utf8, gbk)",
-				Value:   "utf8",
-				EnvVars: []string{"MSSQL_CHARSET"},
-			},
-			&cli.IntFlag{
-				Name:    "timeout",
-				Aliases: []string{"T"},
-				Value:   30,
-				Usage:   "连接超时时间(秒)",
-				EnvVars: []string{"MSSQL_TIMEOUT"},
-			},
-		},
+		// 多值参数（如 --pre-sql）中可能包含逗号，不按逗号拆分
+		DisableSliceFlagSeparator: true,
+		Flags:                     globalFlags(),
 		Commands: []*cli.Command{
 			{
 				Name:    "export",
@@ -91,20 +50,47 @@ func main() {
 				Usage:   "导出数据到CSV文件",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:     "csv",
-						Aliases:  []string{"o"},
-						Usage:    "CSV输出文件路径",
-						Required: true,
+						Name:    "csv",
+						Aliases: []string{"o"},
+						Usage:   "CSV输出文件路径 (与 --table/--sql/--sql-file/--proc 配合使用)",
 					},
 					&cli.StringFlag{
 						Name:    "table",
 						Aliases: []string{"t"},
-						Usage:   "要导出的表名 (与 --sql 二选一)",
+						Usage:   "要导出的表名 (与 --sql/--sql-file/--proc 四选一)",
 					},
 					&cli.StringFlag{
 						Name:    "sql",
 						Aliases: []string{"s"},
-						Usage:   "自定义SQL查询 (与 --table 二选一)",
+						Usage:   "自定义SQL查询 (与 --table/--sql-file/--proc 四选一)",
+					},
+					&cli.StringFlag{
+						Name:  "sql-file",
+						Usage: "从SQL脚本读取导出查询，- 表示标准输入；支持 :setvar 变量和 GO 分批，最后一个批次为导出查询 (与 --table/--sql/--proc 四选一)",
+					},
+					&cli.StringFlag{
+						Name:  "proc",
+						Usage: "要执行的存储过程，每个结果集导出为单独的文件 (如 report_1.csv、report_2.csv)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "param",
+						Usage: "查询参数，格式 名称=值[:类型]，在SQL或 --where 中以 @名称 引用或作为存储过程的同名参数传入，类型缺省为 nvarchar (可多次指定)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "out-param",
+						Usage: "存储过程的输出参数，格式 名称[:类型]，其值与返回值一起输出并记录到清单中 (可多次指定，仅用于 --proc)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "tables",
+						Usage: "多表导出的表匹配模式，逗号分隔 (如 'dbo.*,sales.Orders')，需配合 --out-dir",
+					},
+					&cli.StringSliceFlag{
+						Name:  "exclude-tables",
+						Usage: "多表导出时排除的表匹配模式，逗号分隔",
+					},
+					&cli.StringFlag{
+						Name:  "out-dir",
+						Usage: "多表导出的输出目录，每张表输出为 <schema>.<table>.csv",
 					},
 					&cli.BoolFlag{
 						Name:  "header",
@@ -122,6 +108,22 @@ func main() {
 						Usage:   "限制导出记录数 (0表示无限制)",
 						Value:   0,
 					},
+					&cli.StringSliceFlag{
+						Name:  "columns",
+						Usage: "只导出指定的列，逗号分隔，按指定顺序输出 (仅用于 --table)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "exclude-columns",
+						Usage: "不导出的列，逗号分隔 (仅用于 --table)",
+					},
+					&cli.StringFlag{
+						Name:  "where",
+						Usage: "过滤条件，如 \"status = 'paid' AND amount > @min\"，只能引用表的列、常量、运算符和 --param 定义的 @参数，不能调用函数或子查询 (仅用于 --table)",
+					},
+					&cli.StringFlag{
+						Name:  "order-by",
+						Usage: "排序列，逗号分隔，可加 asc/desc，如 \"created_at desc, id\" (仅用于 --table)",
+					},
 					&cli.StringFlag{
 						Name:    "binary-format",
 						Aliases: []string{"bf"},
@@ -134,6 +136,59 @@ func main() {
 						Usage:   "文件的字符集 {utf8,gbk,latinl}",
 						Value:   "utf8",
 					},
+					&cli.StringSliceFlag{
+						Name:  "mask",
+						Usage: "列脱敏规则，格式 列名=规则 {hash[:长度], fake[:name|email|phone], partial:保留位数, null} (可多次指定)",
+					},
+					&cli.StringFlag{
+						Name:    "mask-key",
+						Usage:   "hash/fake 脱敏规则使用的密钥",
+						EnvVars: []string{"MSSQL_MASK_KEY"},
+					},
+					&cli.StringSliceFlag{
+						Name:  "transform",
+						Usage: "列值转换，格式 列名=函数1|函数2:参数 (如 name=trim|upper，可多次指定)",
+					},
+					&cli.StringFlag{
+						Name:  "transform-file",
+						Usage: "列值转换配置文件 (JSON)",
+					},
+					&cli.BoolFlag{
+						Name:  "manifest",
+						Usage: "导出完成后写入 <文件>.manifest.json 清单 (查询、列、行数、大小、SHA-256)",
+						Value: false,
+					},
+					&cli.StringFlag{
+						Name:  "isolation",
+						Usage: "在指定隔离级别的事务中导出 {read-uncommitted, read-committed, snapshot, repeatable-read}，多表导出使用 snapshot 时各表数据一致",
+					},
+					&cli.BoolFlag{
+						Name:  "nolock",
+						Usage: "表导出时添加 WITH (NOLOCK) 提示 (不加锁，但可能读到未提交、重复或缺失的行)",
+					},
+					&cli.BoolFlag{
+						Name:  "encrypt-output",
+						Usage: "使用口令加密输出文件 (AES-256-GCM)",
+						Value: false,
+					},
+					&cli.StringFlag{
+						Name:    "passphrase",
+						Usage:   "输出文件加密口令",
+						EnvVars: []string{"MSSQL_FILE_PASSPHRASE"},
+					},
+					&cli.StringSliceFlag{
+						Name:  "pre-sql",
+						Usage: "导出前执行的SQL，可为内联SQL或 @file.sql，支持 GO 分批 (可多次指定)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "post-sql",
+						Usage: "导出后执行的SQL，可为内联SQL或 @file.sql，支持 GO 分批 (可多次指定)",
+					},
+					&cli.BoolFlag{
+						Name:  "post-sql-always",
+						Usage: "导出失败时也执行 --post-sql",
+						Value: false,
+					},
 				},
 				Before: validateExportFlags,
 				Action: exportCommand,
@@ -146,7 +201,7 @@ func main() {
 					&cli.StringFlag{
 						Name:     "csv",
 						Aliases:  []string{"i"},
-						Usage:    "CSV输入文件路径",
+						Usage:    "CSV输入文件路径，支持通配符 (如 'dir/*.csv') 或目录",
 						Required: true,
 					},
 					&cli.StringFlag{
@@ -181,6 +236,57 @@ func main() {
 						Usage: "跳过错误行继续导入",
 						Value: false,
 					},
+					&cli.StringFlag{
+						Name:  "source-file-column",
+						Usage: "记录来源文件名的表列名",
+					},
+					&cli.StringFlag{
+						Name:  "archive-dir",
+						Usage: "导入成功后将文件移动到的归档目录",
+					},
+					&cli.StringFlag{
+						Name:  "on-overflow",
+						Usage: "值超出列长度或精度时的处理方式 {error, truncate, reject}",
+						Value: "error",
+					},
+					&cli.StringFlag{
+						Name:  "reject-file",
+						Usage: "被拒绝行的输出文件 (--on-overflow reject 时必填)",
+					},
+					&cli.BoolFlag{
+						Name:  "verify-manifest",
+						Usage: "导入前按 <文件>.manifest.json 校验文件的校验和与行数，不一致时拒绝导入",
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:  "reconcile",
+						Usage: "导入后核对插入行数与表行数 (COUNT_BIG) 的增量",
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:  "reconcile-checksum",
+						Usage: "导入后额外核对表中新增数据与文件的校验和 (隐含 --reconcile)",
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "只校验文件（解析、列匹配、类型转换），不写入任何数据",
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:  "strict",
+						Usage: "校验时额外检查长度、精度以及主键/唯一键重复 (配合 --dry-run)",
+						Value: false,
+					},
+					&cli.StringFlag{
+						Name:  "report",
+						Usage: "校验报告输出路径 (CSV格式，配合 --dry-run)",
+					},
+					&cli.BoolFlag{
+						Name:  "atomic",
+						Usage: "在单个事务中完成整个导入（含 --truncate），失败时全部回滚",
+						Value: false,
+					},
 					&cli.StringFlag{
 						Name:    "binary-format",
 						Aliases: []string{"bf"},
@@ -193,10 +299,240 @@ func main() {
 						Usage:   "文件的字符集 {utf8,gbk,latinl}",
 						Value:   "utf8",
 					},
+					&cli.StringSliceFlag{
+						Name:  "transform",
+						Usage: "列值转换，格式 列名=函数1|函数2:参数 (如 name=trim|upper，可多次指定)",
+					},
+					&cli.StringFlag{
+						Name:  "transform-file",
+						Usage: "列值转换配置文件 (JSON)",
+					},
+					&cli.StringFlag{
+						Name:    "passphrase",
+						Usage:   "加密输入文件的解密口令",
+						EnvVars: []string{"MSSQL_FILE_PASSPHRASE"},
+					},
+					&cli.StringSliceFlag{
+						Name:  "pre-sql",
+						Usage: "导入前执行的SQL，可为内联SQL或 @file.sql，支持 GO 分批 (可多次指定)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "post-sql",
+						Usage: "导入后执行的SQL，可为内联SQL或 @file.sql，支持 GO 分批 (可多次指定)",
+					},
+					&cli.BoolFlag{
+						Name:  "post-sql-always",
+						Usage: "导入失败时也执行 --post-sql",
+						Value: false,
+					},
 				},
 				Before: validateImportFlags,
 				Action: importCommand,
 			},
+			{
+				Name:  "copy",
+				Usage: "在两个数据库之间直接复制表数据 (不经过中间文件)；源连接使用全局参数 (如 --profile、--dsn)，目标连接使用 --target-* 参数；只在建立连接时按 --retries 重试，复制过程中出错不重试",
+				Flags: append(append([]cli.Flag{
+					&cli.StringFlag{
+						Name:    "table",
+						Aliases: []string{"t"},
+						Usage:   "要复制的源表名 (与 --sql 二选一)",
+					},
+					&cli.StringFlag{
+						Name:    "sql",
+						Aliases: []string{"s"},
+						Usage:   "源查询 (与 --table 二选一，需指定 --target-table)",
+					},
+					&cli.StringFlag{
+						Name:  "target-table",
+						Usage: "目标表名 (默认与源表同名)",
+					},
+				}, targetConnFlags()...), []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "create-table",
+						Usage: "目标表不存在时按源表结构创建 (不含外键)",
+						Value: false,
+					},
+					&cli.StringFlag{
+						Name:  "schema-file",
+						Usage: "配合 --create-table，按 schema 命令输出的 JSON 表结构文档建表，而不是读取源表结构",
+					},
+					&cli.BoolFlag{
+						Name:  "truncate",
+						Usage: "复制前清空目标表",
+						Value: false,
+					},
+					&cli.IntFlag{
+						Name:    "batch",
+						Aliases: []string{"b"},
+						Usage:   "批量插入大小",
+						Value:   1000,
+					},
+					&cli.BoolFlag{
+						Name:  "skip-errors",
+						Usage: "跳过错误行继续复制",
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:  "bulk",
+						Usage: "使用批量复制协议写入 (更快，不支持 --skip-errors，自增列由目标表重新生成)",
+						Value: false,
+					},
+				}...),
+				Action: copyCommand,
+			},
+			{
+				Name:  "schema",
+				Usage: "导出表结构定义 (T-SQL 或 JSON)",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "table",
+						Aliases: []string{"t"},
+						Usage:   "要导出结构的表名 (与 --all 二选一)",
+					},
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "导出所有用户表的结构",
+						Value: false,
+					},
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "输出格式 {sql, json}",
+						Value:   "sql",
+					},
+					&cli.StringFlag{
+						Name:    "out",
+						Aliases: []string{"o"},
+						Usage:   "输出文件路径 (默认输出到标准输出)",
+					},
+				},
+				Before: validateSchemaFlags,
+				Action: schemaCommand,
+			},
+			{
+				Name:  "dump",
+				Usage: "转储整个数据库的表结构和数据到目录",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "out-dir",
+						Aliases:  []string{"o"},
+						Usage:    "转储输出目录",
+						Required: true,
+					},
+					&cli.StringSliceFlag{
+						Name:  "tables",
+						Usage: "需要转储的表匹配模式，逗号分隔 (默认所有表)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "exclude-tables",
+						Usage: "排除的表匹配模式，逗号分隔",
+					},
+					&cli.StringFlag{
+						Name:  "isolation",
+						Usage: "导出数据的隔离级别 {read-uncommitted, read-committed, snapshot, repeatable-read}，snapshot 时所有表在同一个快照中导出",
+					},
+				},
+				Before: func(c *cli.Context) error {
+					if _, err := exporter.ParseIsolation(c.String("isolation")); err != nil {
+						return cli.Exit(fmt.Sprintf("错误: %v", err), 1)
+					}
+					if err := validateTablePatterns(c); err != nil {
+						return err
+					}
+					return validateOutDir(c.String("out-dir"))
+				},
+				Action: dumpCommand,
+			},
+			{
+				Name:  "restore",
+				Usage: "从转储目录恢复表结构和数据",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "in-dir",
+						Aliases:  []string{"i"},
+						Usage:    "转储目录 (由 dump 命令生成)",
+						Required: true,
+					},
+					&cli.IntFlag{
+						Name:    "batch",
+						Aliases: []string{"b"},
+						Usage:   "批量插入大小",
+						Value:   1000,
+					},
+					&cli.BoolFlag{
+						Name:  "skip-errors",
+						Usage: "跳过错误行继续导入",
+						Value: false,
+					},
+				},
+				Action: restoreCommand,
+			},
+			{
+				Name:  "diff",
+				Usage: "按键列比较表与CSV文件或另一张表的数据差异",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "table",
+						Aliases:  []string{"t"},
+						Usage:    "基准表名",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:    "csv",
+						Aliases: []string{"c"},
+						Usage:   "与基准表比较的CSV文件 (与 --target-table 二选一)",
+					},
+					&cli.StringFlag{
+						Name:  "target-table",
+						Usage: "与基准表比较的表名，可配合 --target-* 参数位于其他数据库 (与 --csv 二选一)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "key",
+						Usage: "键列，逗号分隔 (默认使用主键)",
+					},
+					&cli.IntFlag{
+						Name:  "chunk-size",
+						Usage: "每个校验分块的大致行数",
+						Value: 10000,
+					},
+					&cli.StringFlag{
+						Name:    "out",
+						Aliases: []string{"o"},
+						Usage:   "差异输出文件路径 (默认输出到标准输出)",
+					},
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "差异输出格式 {csv, json}",
+						Value:   "csv",
+					},
+					&cli.BoolFlag{
+						Name:  "header",
+						Usage: "CSV文件包含列标题",
+						Value: true,
+					},
+					&cli.StringFlag{
+						Name:  "delimiter",
+						Usage: "CSV分隔符",
+						Value: ",",
+					},
+					&cli.StringFlag{
+						Name:    "binary-format",
+						Aliases: []string{"bf"},
+						Usage:   "二进制数格式 {hex, base64, raw}",
+						Value:   "raw",
+					},
+					&cli.StringFlag{
+						Name:    "file-charset",
+						Aliases: []string{"fc"},
+						Usage:   "文件的字符集 {utf8,gbk,latinl}",
+						Value:   "utf8",
+					},
+				}, targetConnFlags()...),
+				Before: validateDiffFlags,
+				Action: diffCommand,
+			},
 			{
 				Name:    "test",
 				Aliases: []string{"t"},
@@ -204,50 +540,454 @@ func main() {
 				Action:  testConnection,
 			},
 		},
-		Before: func(c *cli.Context) error {
-			if c.String("password") == "" {
-				return cli.Exit("错误: 密码不能为空，请通过 -password 参数或环境变量设置", 1)
-			}
-			return nil
+		Action: func(c *cli.Context) error {
+			cli.ShowAppHelp(c)
+			return nil
+		},
+		ExitErrHandler: func(c *cli.Context, err error) {
+			if err != nil {
+				fmt.Fprintf(c.App.Writer, "❌ 错误: %v\n", err)
+			}
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		os.Exit(1)
+	}
+}
+
+// 元数据中缓存合并后的数据库配置的键
+const dbConfigKey = "dbConfig"
+
+// 构建数据库配置
+// 合并优先级为 命令行参数 > 环境变量 > 连接配置 > 参数默认值，结果在本次运行中缓存，
+// 避免重复执行 password_cmd
+func buildDBConfig(c *cli.Context) (config.DBConfig, error) {
+	if cfg, ok := c.App.Metadata[dbConfigKey].(config.DBConfig); ok {
+		return cfg, nil
+	}
+
+	// --charset 从未被驱动使用：TDS 以 Unicode 传输 nchar/nvarchar，varchar 按排序规则的代码页转换
+	if c.IsSet("charset") {
+		fmt.Fprintln(os.Stderr, "⚠️  --charset 已弃用且不起作用，请改用 --file-charset 指定文件字符集")
+	}
+
+	cfg := config.DBConfig{
+		Server:   c.String("server"),
+		Port:     uint64(c.Int("port")),
+		User:     c.String("user"),
+		Password: c.String("password"),
+		DBName:   c.String("db"),
+		Encrypt:  c.String("encrypt"),
+		Timeout:  uint64(c.Int("timeout")),
+		Retry:    buildRetryConfig(c),
+
+		MaxOpenConns:    max(c.Int("max-open-conns"), 0),
+		MaxIdleConns:    max(c.Int("max-idle-conns"), 0),
+		ConnMaxLifetime: c.Duration("conn-max-lifetime"),
+		ConnMaxIdleTime: c.Duration("conn-max-idle-time"),
+		SessionSet:      c.StringSlice("session-set"),
+	}
+
+	if name := c.String("profile"); name != "" {
+		if c.String("dsn") != "" {
+			return cfg, fmt.Errorf("--dsn 与 --profile 不能同时使用")
+		}
+		p, err := profile.Load(c.String("profile-file"), name)
+		if err != nil {
+			return cfg, err
+		}
+		if err := applyProfile(&cfg, p, func(flag string) bool { return c.IsSet(flag) }); err != nil {
+			return cfg, err
+		}
+		cfg.Profile = name
+	}
+
+	if dsn := c.String("dsn"); dsn != "" {
+		d, err := conn.ParseDSN(dsn)
+		if err != nil {
+			return cfg, err
+		}
+		applyDSN(&cfg, d, func(flag string) bool { return c.IsSet(flag) })
+	}
+
+	var err error
+	if cfg.Params, err = conn.ParseParams(c.StringSlice("conn-param")); err != nil {
+		return cfg, err
+	}
+
+	// 连接字符串可能使用其他认证方式或登录的默认数据库，不强制要求密码和数据库名
+	if cfg.DSN == "" {
+		if cfg.Password == "" {
+			return cfg, fmt.Errorf("密码不能为空，请通过 --password 参数、环境变量或连接配置设置")
+		}
+		if cfg.DBName == "" {
+			return cfg, fmt.Errorf("数据库名不能为空，请通过 --db 参数、环境变量或连接配置设置")
+		}
+	}
+
+	c.App.Metadata[dbConfigKey] = cfg
+	return cfg, nil
+}
+
+// 构建重试策略
+func buildRetryConfig(c *cli.Context) config.RetryConfig {
+	return config.RetryConfig{
+		Retries: max(c.Int("retries"), 0),
+		Backoff: c.Duration("retry-backoff"),
+	}
+}
+
+// applyProfile 用连接配置填充未通过命令行参数或环境变量设置的字段
+// isSet 判断对应的参数是否已显式设置
+func applyProfile(cfg *config.DBConfig, p *profile.Profile, isSet func(flag string) bool) error {
+	if p.Server != "" && !isSet("server") {
+		cfg.Server = p.Server
+	}
+	if p.Port != 0 && !isSet("port") {
+		cfg.Port = uint64(p.Port)
+	}
+	if p.User != "" && !isSet("user") {
+		cfg.User = p.User
+	}
+	if p.DB != "" && !isSet("db") {
+		cfg.DBName = p.DB
+	}
+	if p.Encrypt != "" && !isSet("encrypt") {
+		cfg.Encrypt = p.Encrypt
+	}
+	if p.Charset != "" {
+		fmt.Fprintf(os.Stderr, "⚠️  连接配置 %s 中的 charset 已弃用且不起作用，请改用 --file-charset 指定文件字符集\n", p.Name)
+	}
+	if p.Timeout != 0 && !isSet("timeout") {
+		cfg.Timeout = uint64(p.Timeout)
+	}
+	if !isSet("password") {
+		password, err := p.ResolvePassword()
+		if err != nil {
+			return err
+		}
+		if password != "" {
+			cfg.Password = password
+		}
+	}
+	return nil
+}
+
+// 构建前置/后置SQL配置
+func buildHookConfig(c *cli.Context) (config.HookConfig, error) {
+	pre, err := hooks.Load(c.StringSlice("pre-sql"))
+	if err != nil {
+		return config.HookConfig{}, fmt.Errorf("加载 --pre-sql 失败: %w", err)
+	}
+	post, err := hooks.Load(c.StringSlice("post-sql"))
+	if err != nil {
+		return config.HookConfig{}, fmt.Errorf("加载 --post-sql 失败: %w", err)
+	}
+	return config.HookConfig{
+		PreSQL:        pre,
+		PostSQL:       post,
+		PostSQLAlways: c.Bool("post-sql-always"),
+	}, nil
+}
+
+// applyDSN 使用连接字符串中的连接参数替换未显式设置的参数
+// 加密方式和超时未显式设置时由连接字符串决定
+func applyDSN(cfg *config.DBConfig, d config.DBConfig, isSet func(flag string) bool) {
+	cfg.DSN = d.DSN
+	if !isSet("server") {
+		cfg.Server = d.Server
+	}
+	if !isSet("port") {
+		cfg.Port = d.Port
+	}
+	if !isSet("user") {
+		cfg.User = d.User
+	}
+	if !isSet("password") {
+		cfg.Password = d.Password
+	}
+	if !isSet("db") {
+		cfg.DBName = d.DBName
+	}
+	if !isSet("encrypt") {
+		cfg.Encrypt = ""
+	}
+	if !isSet("timeout") {
+		cfg.Timeout = 0
+	}
+}
+
+// 构建目标数据库配置
+// 优先级为 --target-* 参数 > --target-dsn 连接字符串或 --target-profile 连接配置 > 源数据库配置
+func buildTargetDBConfig(c *cli.Context) (config.DBConfig, error) {
+	cfg, err := buildDBConfig(c)
+	if err != nil {
+		return cfg, err
+	}
+
+	// 目标连接字符串或连接配置描述的是另一个连接，不沿用源连接的连接字符串和 --conn-param，
+	// 避免源连接的 ApplicationIntent、TrustServerCertificate、encrypt 等参数带到目标连接
+	if c.String("target-dsn") != "" || c.String("target-profile") != "" {
+		cfg.DSN = ""
+		cfg.Params = nil
+	}
+
+	if dsn := c.String("target-dsn"); dsn != "" {
+		if c.String("target-profile") != "" {
+			return cfg, fmt.Errorf("--target-dsn 与 --target-profile 不能同时使用")
+		}
+		d, err := conn.ParseDSN(dsn)
+		if err != nil {
+			return cfg, err
+		}
+		// 超时沿用源连接，其余参数由目标连接字符串决定
+		applyDSN(&cfg, d, func(flag string) bool { return flag == "timeout" })
+	}
+
+	if name := c.String("target-profile"); name != "" {
+		p, err := profile.Load(c.String("profile-file"), name)
+		if err != nil {
+			return cfg, err
+		}
+		// 源连接的参数不影响目标连接配置，只有 --target-* 参数优先于目标连接配置
+		if err := applyProfile(&cfg, p, func(string) bool { return false }); err != nil {
+			return cfg, err
+		}
+		// 目标连接配置为未指定端口的命名实例时不沿用源连接的端口，由 SQL Server Browser 解析
+		if strings.Contains(p.Server, `\`) && p.Port == 0 {
+			cfg.Port = 0
+		}
+		cfg.Profile = name
+	}
+
+	if c.IsSet("target-server") {
+		cfg.Server = c.String("target-server")
+		// 目标为命名实例时不沿用源连接的端口，由 SQL Server Browser 解析
+		if strings.Contains(cfg.Server, `\`) && !c.IsSet("target-port") {
+			cfg.Port = 0
+		}
+	}
+	if c.IsSet("target-port") {
+		cfg.Port = uint64(c.Int("target-port"))
+	}
+	if c.IsSet("target-user") {
+		cfg.User = c.String("target-user")
+	}
+	if c.IsSet("target-password") {
+		cfg.Password = c.String("target-password")
+	}
+	if c.IsSet("target-db") {
+		cfg.DBName = c.String("target-db")
+	}
+	if c.IsSet("target-encrypt") {
+		cfg.Encrypt = c.String("target-encrypt")
+	}
+
+	// --target-conn-param 叠加在目标连接参数之上，复制一份避免修改源连接的参数
+	targetParams, err := conn.ParseParams(c.StringSlice("target-conn-param"))
+	if err != nil {
+		return cfg, err
+	}
+	if len(targetParams) > 0 {
+		params := make(map[string]string, len(cfg.Params)+len(targetParams))
+		for k, v := range cfg.Params {
+			params[k] = v
+		}
+		for k, v := range targetParams {
+			params[k] = v
+		}
+		cfg.Params = params
+	}
+	return cfg, nil
+}
+
+// 全局连接参数
+func globalFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "server",
+			Aliases: []string{"S"},
+			Value:   "localhost",
+			Usage:   "SQL Server地址，命名实例使用 HOST\\INSTANCE 格式",
+			EnvVars: []string{"MSSQL_SERVER", "DB_SERVER"},
+		},
+		&cli.IntFlag{
+			Name:    "port",
+			Aliases: []string{"P"},
+			Usage:   "SQL Server端口 (默认 1433，命名实例未指定时通过 SQL Server Browser 解析)",
+			EnvVars: []string{"MSSQL_PORT", "DB_PORT"},
 		},
-		Action: func(c *cli.Context) error {
-			cli.ShowAppHelp(c)
-			return nil
+		&cli.StringFlag{
+			Name:    "user",
+			Aliases: []string{"U"},
+			Value:   "sa",
+			Usage:   "数据库用户名",
+			EnvVars: []string{"MSSQL_USER", "DB_USER"},
 		},
-		ExitErrHandler: func(c *cli.Context, err error) {
-			if err != nil {
-				fmt.Fprintf(c.App.Writer, "❌ 错误: %v\n", err)
-			}
+		&cli.StringFlag{
+			Name:    "password",
+			Aliases: []string{"W"},
+			Usage:   "数据库密码 (必填，可由连接配置提供)",
+			EnvVars: []string{"MSSQL_PASSWORD", "DB_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:    "db",
+			Aliases: []string{"D"},
+			Usage:   "数据库名 (必填，可由连接配置提供)",
+			EnvVars: []string{"MSSQL_DBNAME", "DB_NAME"},
+		},
+		&cli.StringFlag{
+			Name:    "encrypt",
+			Aliases: []string{"E"},
+			Value:   "off",
+			Usage:   "是否启用加密连接",
+			EnvVars: []string{"MSSQL_ENCRYPT"},
+		},
+		&cli.StringFlag{
+			Name:    "charset",
+			Aliases: []string{"C"},
+			Usage:   "已弃用，无效果：驱动始终以 Unicode 读写文本，文件字符集请使用 --file-charset",
+			EnvVars: []string{"MSSQL_CHARSET"},
+		},
+		&cli.IntFlag{
+			Name:    "timeout",
+			Aliases: []string{"T"},
+			Value:   30,
+			Usage:   "连接超时时间(秒)",
+			EnvVars: []string{"MSSQL_TIMEOUT"},
+		},
+		&cli.StringFlag{
+			Name:    "profile",
+			Usage:   "使用连接配置文件中的命名连接配置 (命令行参数和环境变量优先)",
+			EnvVars: []string{"MSSQL_PROFILE"},
+		},
+		&cli.StringFlag{
+			Name:    "profile-file",
+			Usage:   "连接配置文件路径",
+			Value:   profile.DefaultPath(),
+			EnvVars: []string{"MSSQL_PROFILE_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "dsn",
+			Usage:   "完整的连接字符串 (sqlserver://... 或 ADO 格式)，显式指定的连接参数覆盖其中的同名项",
+			EnvVars: []string{"MSSQL_DSN"},
+		},
+		&cli.IntFlag{
+			Name:    "retries",
+			Value:   3,
+			Usage:   "遇到暂时性错误（死锁、故障转移、连接中断等）时的最大重试次数，0 表示不重试 (copy 只在建立连接时重试)",
+			EnvVars: []string{"MSSQL_RETRIES"},
+		},
+		&cli.DurationFlag{
+			Name:    "retry-backoff",
+			Value:   time.Second,
+			Usage:   "首次重试前的等待时间，之后每次翻倍 (最长 30s)",
+			EnvVars: []string{"MSSQL_RETRY_BACKOFF"},
+		},
+		&cli.IntFlag{
+			Name:    "max-open-conns",
+			Value:   10,
+			Usage:   "连接池最大打开连接数，0 表示不限制 (同时决定多表导出的并发数)",
+			EnvVars: []string{"MSSQL_MAX_OPEN_CONNS"},
+		},
+		&cli.IntFlag{
+			Name:    "max-idle-conns",
+			Value:   5,
+			Usage:   "连接池最大空闲连接数，0 表示不保留空闲连接",
+			EnvVars: []string{"MSSQL_MAX_IDLE_CONNS"},
+		},
+		&cli.DurationFlag{
+			Name:    "conn-max-lifetime",
+			Value:   5 * time.Minute,
+			Usage:   "连接最大存活时间，0 表示不限制",
+			EnvVars: []string{"MSSQL_CONN_MAX_LIFETIME"},
+		},
+		&cli.DurationFlag{
+			Name:    "conn-max-idle-time",
+			Usage:   "连接最大空闲时间，0 表示不限制",
+			EnvVars: []string{"MSSQL_CONN_MAX_IDLE_TIME"},
+		},
+		&cli.StringSliceFlag{
+			Name:  "session-set",
+			Usage: "在每个连接上执行的会话设置 (可多次指定，如 \"LOCK_TIMEOUT 5000\"、\"SET DEADLOCK_PRIORITY LOW\")",
+		},
+		&cli.StringSliceFlag{
+			Name:  "conn-param",
+			Usage: "透传给驱动的连接参数 key=value (可多次指定，如 \"app name=etl\"、ApplicationIntent=ReadOnly)",
 		},
-	}
-
-	if err := app.Run(os.Args); err != nil {
-		os.Exit(1)
 	}
 }
 
-// 构建数据库配置
-func buildDBConfig(c *cli.Context) config.DBConfig {
-	return config.DBConfig{
-		Server:   c.String("server"),
-		Port:     uint64(c.Int("port")),
-		User:     c.String("user"),
-		Password: c.String("password"),
-		DBName:   c.String("db"),
-		Encrypt:  c.String("encrypt"),
-		Charset:  c.String("charset"),
-		Timeout:  uint64(c.Int("timeout")),
+// 目标数据库连接参数，未指定的参数沿用源数据库配置
+func targetConnFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "target-profile",
+			Usage: "目标连接使用的连接配置名 (--target-* 参数优先)",
+		},
+		&cli.StringFlag{
+			Name:  "target-dsn",
+			Usage: "目标连接的完整连接字符串 (--target-* 参数优先)",
+		},
+		&cli.StringFlag{
+			Name:  "target-server",
+			Usage: "目标SQL Server地址 (默认与源相同)",
+		},
+		&cli.IntFlag{
+			Name:  "target-port",
+			Usage: "目标SQL Server端口 (默认与源相同)",
+		},
+		&cli.StringFlag{
+			Name:  "target-user",
+			Usage: "目标数据库用户名 (默认与源相同)",
+		},
+		&cli.StringFlag{
+			Name:    "target-password",
+			Usage:   "目标数据库密码 (默认与源相同)",
+			EnvVars: []string{"MSSQL_TARGET_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:  "target-db",
+			Usage: "目标数据库名 (默认与源相同)",
+		},
+		&cli.StringFlag{
+			Name:  "target-encrypt",
+			Usage: "目标连接是否启用加密 (默认与源相同)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "target-conn-param",
+			Usage: "透传给驱动的目标连接参数 key=value (可多次指定)；使用 --target-dsn 或 --target-profile 时不沿用源连接的 --dsn 和 --conn-param",
+		},
 	}
 }
 
 // 连接数据库
 func connectDB(c *cli.Context) (*sql.DB, error) {
-	dbCfg := buildDBConfig(c)
+	dbCfg, err := buildDBConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return conn.Connect(dbCfg)
+}
+
+// 连接目标数据库
+func connectTargetDB(c *cli.Context) (*sql.DB, error) {
+	dbCfg, err := buildTargetDBConfig(c)
+	if err != nil {
+		return nil, err
+	}
 	return conn.Connect(dbCfg)
 }
 
 // 导出命令
 func exportCommand(c *cli.Context) error {
+	// 在连接之前读取SQL脚本：连接配置的 password_cmd 继承标准输入，先执行会读走 --sql-file - 传入的脚本
+	setup, query, err := loadExportSQL(c)
+	if err != nil {
+		return err
+	}
+
 	db, err := connectDB(c)
 	if err != nil {
 		return fmt.Errorf("数据库连接失败: %w", err)
@@ -267,13 +1007,69 @@ func exportCommand(c *cli.Context) error {
 
 	cfg := config.ExportConfig{
 		Table:        c.String("table"),
-		SQL:          c.String("sql"),
 		CSVPath:      c.String("csv"),
 		Header:       c.Bool("header"),
 		Delimiter:    delimiter,
 		Limit:        c.Int("limit"),
 		BinaryFormat: c.String("binary-format"),
 		FileCharset:  c.String("file-charset"),
+
+		Tables:        exporter.SplitPatterns(c.StringSlice("tables")),
+		ExcludeTables: exporter.SplitPatterns(c.StringSlice("exclude-tables")),
+		OutDir:        c.String("out-dir"),
+		Manifest:      c.Bool("manifest"),
+		Retry:         buildRetryConfig(c),
+		Isolation:     c.String("isolation"),
+		NoLock:        c.Bool("nolock"),
+
+		Columns:        exporter.SplitPatterns(c.StringSlice("columns")),
+		ExcludeColumns: exporter.SplitPatterns(c.StringSlice("exclude-columns")),
+		Where:          c.String("where"),
+		OrderBy:        c.String("order-by"),
+		Proc:           c.String("proc"),
+	}
+	if cfg.Manifest {
+		dbCfg, err := buildDBConfig(c)
+		if err != nil {
+			return err
+		}
+		cfg.Server, cfg.Database = dbCfg.Server, dbCfg.DBName
+	}
+	if cfg.Params, err = sqlparams.Parse(c.StringSlice("param")); err != nil {
+		return err
+	}
+	if cfg.OutParams, err = sqlparams.ParseOutputs(c.StringSlice("out-param"), cfg.Params); err != nil {
+		return err
+	}
+	if cfg.Hooks, err = buildHookConfig(c); err != nil {
+		return err
+	}
+	cfg.SQL = query
+	cfg.SetupSQL = setup
+	if cfg.Transforms, err = transforms.Load(c.StringSlice("transform"), c.String("transform-file")); err != nil {
+		return fmt.Errorf("加载列值转换失败: %w", err)
+	}
+	if cfg.Masks, err = masking.Parse(c.StringSlice("mask"), c.String("mask-key")); err != nil {
+		return fmt.Errorf("加载脱敏规则失败: %w", err)
+	}
+	if c.Bool("encrypt-output") {
+		cfg.Passphrase = c.String("passphrase")
+	}
+
+	if len(cfg.Tables) > 0 {
+		if err := exporter.TablesToDir(db, cfg); err != nil {
+			return fmt.Errorf("多表导出失败: %w", err)
+		}
+		fmt.Printf("✅ 导出成功: 数据已保存到目录 %s\n", cfg.OutDir)
+		return nil
+	}
+
+	if cfg.Proc != "" {
+		if err := exporter.ProcToCSV(db, cfg); err != nil {
+			return fmt.Errorf("导出存储过程结果失败: %w", err)
+		}
+		fmt.Println("✅ 导出成功")
+		return nil
 	}
 
 	if cfg.Table != "" {
@@ -290,6 +1086,36 @@ func exportCommand(c *cli.Context) error {
 	return nil
 }
 
+// loadExportSQL 返回 --sql 指定的查询，或读取 --sql-file 指定的脚本（- 表示标准输入）
+// 脚本支持 :setvar 变量和 GO 分批：最后一个批次作为导出查询，之前的批次作为准备批次返回
+func loadExportSQL(c *cli.Context) ([]string, string, error) {
+	path := c.String("sql-file")
+	if path == "" {
+		return nil, c.String("sql"), nil
+	}
+
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("读取SQL文件失败: %w", err)
+	}
+
+	batches, err := sqlscript.Parse(string(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("解析SQL文件失败: %w", err)
+	}
+	if len(batches) == 0 {
+		return nil, "", fmt.Errorf("SQL文件 %s 为空", path)
+	}
+	last := len(batches) - 1
+	return batches[:last], strings.TrimSpace(batches[last]), nil
+}
+
 // 导入命令
 func importCommand(c *cli.Context) error {
 	db, err := connectDB(c)
@@ -319,16 +1145,241 @@ func importCommand(c *cli.Context) error {
 		SkipErrors:   c.Bool("skip-errors"),
 		BinaryFormat: c.String("binary-format"),
 		FileCharset:  c.String("file-charset"),
+		Atomic:       c.Bool("atomic"),
+
+		SourceFileColumn:  c.String("source-file-column"),
+		ArchiveDir:        c.String("archive-dir"),
+		Passphrase:        c.String("passphrase"),
+		VerifyManifest:    c.Bool("verify-manifest"),
+		Reconcile:         c.Bool("reconcile") || c.Bool("reconcile-checksum"),
+		ReconcileChecksum: c.Bool("reconcile-checksum"),
+		OnOverflow:        c.String("on-overflow"),
+		RejectPath:        c.String("reject-file"),
+		DryRun:            c.Bool("dry-run"),
+		Strict:            c.Bool("strict"),
+		ReportPath:        c.String("report"),
+		Retry:             buildRetryConfig(c),
+	}
+	if cfg.Hooks, err = buildHookConfig(c); err != nil {
+		return err
+	}
+	if cfg.Transforms, err = transforms.Load(c.StringSlice("transform"), c.String("transform-file")); err != nil {
+		return fmt.Errorf("加载列值转换失败: %w", err)
 	}
 
-	if err := importer.CSVToTable(db, cfg); err != nil {
+	files, err := importer.ExpandInputs(cfg.CSVPath)
+	if err != nil {
+		return err
+	}
+	if err := importer.FilesToTable(db, cfg, files); err != nil {
+		if cfg.DryRun {
+			return fmt.Errorf("校验失败: %w", err)
+		}
 		return fmt.Errorf("导入失败: %w", err)
 	}
+	if cfg.DryRun {
+		return nil
+	}
 
 	fmt.Printf("✅ 导入成功: 数据已导入到表 %s\n", cfg.Table)
 	return nil
 }
 
+// 跨库复制命令
+func copyCommand(c *cli.Context) error {
+	src, err := connectDB(c)
+	if err != nil {
+		return fmt.Errorf("源数据库连接失败: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := connectTargetDB(c)
+	if err != nil {
+		return fmt.Errorf("目标数据库连接失败: %w", err)
+	}
+	defer dst.Close()
+
+	cfg := config.CopyConfig{
+		Table:       c.String("table"),
+		SQL:         c.String("sql"),
+		TargetTable: c.String("target-table"),
+		CreateTable: c.Bool("create-table"),
+		SchemaFile:  c.String("schema-file"),
+		Truncate:    c.Bool("truncate"),
+		Batch:       c.Int("batch"),
+		SkipErrors:  c.Bool("skip-errors"),
+		Bulk:        c.Bool("bulk"),
+	}
+
+	if err := copier.Copy(src, dst, cfg); err != nil {
+		return fmt.Errorf("复制失败: %w", err)
+	}
+
+	fmt.Println("✅ 复制成功")
+	return nil
+}
+
+// 表结构导出命令
+func schemaCommand(c *cli.Context) error {
+	db, err := connectDB(c)
+	if err != nil {
+		return fmt.Errorf("数据库连接失败: %w", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	var names [][2]string
+	if c.Bool("all") {
+		tables, err := exporter.ResolveTables(ctx, db, []string{"*.*"}, nil)
+		if err != nil {
+			return err
+		}
+		for _, t := range tables {
+			names = append(names, [2]string{t.Schema, t.Name})
+		}
+	} else {
+		schemaName, tableName, err := utils.SplitQualifiedName(c.String("table"))
+		if err != nil {
+			return fmt.Errorf("无效的表名格式: %w", err)
+		}
+		names = append(names, [2]string{schemaName, tableName})
+	}
+
+	tables, err := schema.LoadTables(ctx, db, names)
+	if err != nil {
+		return fmt.Errorf("读取表结构失败: %w", err)
+	}
+	dbCfg, err := buildDBConfig(c)
+	if err != nil {
+		return err
+	}
+	doc := schema.NewDocument(dbCfg.DBName, tables)
+
+	out := os.Stdout
+	if path := c.String("out"); path != "" {
+		if out, err = os.Create(path); err != nil {
+			return fmt.Errorf("创建输出文件失败: %w", err)
+		}
+		defer out.Close()
+	}
+
+	if c.String("format") == "json" {
+		err = doc.WriteJSON(out)
+	} else {
+		err = doc.WriteSQL(out)
+	}
+	if err != nil {
+		return err
+	}
+
+	if out != os.Stdout {
+		fmt.Printf("✅ 表结构导出成功: 共 %d 张表，文件路径: %s\n", len(tables), c.String("out"))
+	}
+	return nil
+}
+
+// 转储命令
+func dumpCommand(c *cli.Context) error {
+	db, err := connectDB(c)
+	if err != nil {
+		return fmt.Errorf("数据库连接失败: %w", err)
+	}
+	defer db.Close()
+
+	dbCfg, err := buildDBConfig(c)
+	if err != nil {
+		return err
+	}
+
+	cfg := config.DumpConfig{
+		OutDir:        c.String("out-dir"),
+		Tables:        exporter.SplitPatterns(c.StringSlice("tables")),
+		ExcludeTables: exporter.SplitPatterns(c.StringSlice("exclude-tables")),
+		Server:        dbCfg.Server,
+		Database:      dbCfg.DBName,
+		Retry:         buildRetryConfig(c),
+		Isolation:     c.String("isolation"),
+	}
+
+	if err := dump.Dump(db, cfg); err != nil {
+		return fmt.Errorf("转储失败: %w", err)
+	}
+
+	fmt.Printf("✅ 转储成功: 表结构和数据已保存到目录 %s\n", cfg.OutDir)
+	return nil
+}
+
+// 恢复命令
+func restoreCommand(c *cli.Context) error {
+	if c.Int("batch") <= 0 {
+		return cli.Exit("错误: --batch 参数必须大于0", 1)
+	}
+
+	db, err := connectDB(c)
+	if err != nil {
+		return fmt.Errorf("数据库连接失败: %w", err)
+	}
+	defer db.Close()
+
+	cfg := config.RestoreConfig{
+		InDir:      c.String("in-dir"),
+		Batch:      c.Int("batch"),
+		SkipErrors: c.Bool("skip-errors"),
+		Retry:      buildRetryConfig(c),
+	}
+
+	if err := dump.Restore(db, cfg); err != nil {
+		return fmt.Errorf("恢复失败: %w", err)
+	}
+
+	dbCfg, _ := buildDBConfig(c)
+	fmt.Printf("✅ 恢复成功: 已从目录 %s 恢复到数据库 %s\n", cfg.InDir, dbCfg.DBName)
+	return nil
+}
+
+// 数据比较命令
+func diffCommand(c *cli.Context) error {
+	db, err := connectDB(c)
+	if err != nil {
+		return fmt.Errorf("数据库连接失败: %w", err)
+	}
+	defer db.Close()
+
+	// 比较另一张表时才需要目标连接
+	other := db
+	if c.String("target-table") != "" {
+		if other, err = connectTargetDB(c); err != nil {
+			return fmt.Errorf("目标数据库连接失败: %w", err)
+		}
+		defer other.Close()
+	}
+
+	// 解析分隔符
+	delimiter := ','
+	if delim := c.String("delimiter"); len(delim) > 0 {
+		delimiter = []rune(delim)[0]
+	}
+
+	cfg := config.DiffConfig{
+		Table:        c.String("table"),
+		CSVPath:      c.String("csv"),
+		TargetTable:  c.String("target-table"),
+		Keys:         exporter.SplitPatterns(c.StringSlice("key")),
+		ChunkSize:    c.Int("chunk-size"),
+		Header:       c.Bool("header"),
+		Delimiter:    delimiter,
+		BinaryFormat: c.String("binary-format"),
+		FileCharset:  c.String("file-charset"),
+		Output:       c.String("out"),
+		Format:       c.String("format"),
+	}
+
+	if err := differ.Diff(db, other, cfg); err != nil {
+		return fmt.Errorf("比较失败: %w", err)
+	}
+	return nil
+}
+
 // 测试连接命令
 func testConnection(c *cli.Context) error {
 	db, err := connectDB(c)
@@ -350,7 +1401,15 @@ func testConnection(c *cli.Context) error {
 
 	fmt.Println("✅ 数据库连接测试成功!")
 	fmt.Printf("   数据库: %s\n", dbName)
-	fmt.Printf("   服务器: %s:%d\n", c.String("server"), c.Int("port"))
+	dbCfg, _ := buildDBConfig(c)
+	if dbCfg.Port > 0 {
+		fmt.Printf("   服务器: %s:%d\n", dbCfg.Server, dbCfg.Port)
+	} else {
+		fmt.Printf("   服务器: %s\n", dbCfg.Server)
+	}
+	if dbCfg.Profile != "" {
+		fmt.Printf("   连接配置: %s\n", dbCfg.Profile)
+	}
 	fmt.Printf("   版本: %s\n", version)
 
 	return nil
@@ -360,18 +1419,79 @@ func testConnection(c *cli.Context) error {
 func validateExportFlags(c *cli.Context) error {
 	table := c.String("table")
 	sql := c.String("sql")
+	sqlFile := c.String("sql-file")
+	proc := c.String("proc")
 	csv := c.String("csv")
 
+	if c.Bool("encrypt-output") && c.String("passphrase") == "" {
+		return cli.Exit("错误: --encrypt-output 需要通过 --passphrase 或环境变量 MSSQL_FILE_PASSPHRASE 指定口令", 1)
+	}
+	if _, err := exporter.ParseIsolation(c.String("isolation")); err != nil {
+		return cli.Exit(fmt.Sprintf("错误: %v", err), 1)
+	}
+
+	// 列选择、过滤和排序只适用于 --table，--sql 可直接在查询中编写
+	hasColumnOptions := len(c.StringSlice("columns")) > 0 || len(c.StringSlice("exclude-columns")) > 0 ||
+		c.String("where") != "" || c.String("order-by") != ""
+	if hasColumnOptions && table == "" {
+		return cli.Exit("错误: --columns、--exclude-columns、--where 和 --order-by 只能与 --table 一起使用", 1)
+	}
+
+	// 查询参数只适用于自定义SQL、存储过程和 --where，提前解析以便在连接数据库前报告无效的值
+	params, err := sqlparams.Parse(c.StringSlice("param"))
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("错误: %v", err), 1)
+	}
+	if len(params) > 0 && sql == "" && sqlFile == "" && proc == "" && c.String("where") == "" {
+		return cli.Exit("错误: --param 只能与 --sql、--sql-file、--proc 或 --where 一起使用", 1)
+	}
+	if outParams := c.StringSlice("out-param"); len(outParams) > 0 {
+		if proc == "" {
+			return cli.Exit("错误: --out-param 只能与 --proc 一起使用", 1)
+		}
+		if _, err := sqlparams.ParseOutputs(outParams, params); err != nil {
+			return cli.Exit(fmt.Sprintf("错误: %v", err), 1)
+		}
+	}
+
+	// 多表导出
+	if len(c.StringSlice("tables")) > 0 {
+		if table != "" || sql != "" || sqlFile != "" || proc != "" || csv != "" {
+			return cli.Exit("错误: --tables 不能与 --table、--sql、--sql-file、--proc 或 --csv 同时使用", 1)
+		}
+		if err := validateTablePatterns(c); err != nil {
+			return err
+		}
+		return validateOutDir(c.String("out-dir"))
+	}
+
 	if csv == "" {
 		return cli.Exit("错误: 必须指定 --csv 参数", 1)
 	}
 
-	if (table == "" && sql == "") || (table != "" && sql != "") {
-		return cli.Exit("错误: 必须且只能指定 --table 或 --sql 参数之一", 1)
+	sources := 0
+	for _, v := range []string{table, sql, sqlFile, proc} {
+		if v != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return cli.Exit("错误: 必须且只能指定 --table、--sql、--sql-file 或 --proc 参数之一", 1)
+	}
+
+	if len(c.StringSlice("columns")) > 0 && len(c.StringSlice("exclude-columns")) > 0 {
+		return cli.Exit("错误: --columns 不能与 --exclude-columns 同时使用", 1)
 	}
 
-	// 检查文件是否可以创建
+	// 检查文件是否可以创建，存储过程导出时检查第一个结果集的文件
+	if proc != "" {
+		csv = exporter.ResultSetPath(csv, 1)
+	}
 	if _, err := os.Stat(csv); err == nil {
+		// 标准输入用于读取SQL脚本时无法确认是否覆盖
+		if sqlFile == "-" {
+			return cli.Exit(fmt.Sprintf("错误: 文件 %s 已存在，从标准输入读取SQL时请先删除该文件或指定其他输出路径", csv), 1)
+		}
 		// 文件已存在，询问是否覆盖
 		fmt.Printf("警告: 文件 %s 已存在，是否覆盖? (y/N): ", csv)
 		var response string
@@ -384,6 +1504,74 @@ func validateExportFlags(c *cli.Context) error {
 	return nil
 }
 
+// 表匹配模式验证（--tables 和 --exclude-tables）
+func validateTablePatterns(c *cli.Context) error {
+	patterns := exporter.SplitPatterns(c.StringSlice("tables"))
+	patterns = append(patterns, exporter.SplitPatterns(c.StringSlice("exclude-tables"))...)
+	if err := exporter.ValidatePatterns(patterns); err != nil {
+		return cli.Exit(fmt.Sprintf("错误: %v", err), 1)
+	}
+	return nil
+}
+
+// 表结构导出参数验证
+func validateSchemaFlags(c *cli.Context) error {
+	if (c.String("table") == "") == !c.Bool("all") {
+		return cli.Exit("错误: 必须且只能指定 --table 或 --all 参数之一", 1)
+	}
+
+	switch c.String("format") {
+	case "sql", "json":
+	default:
+		return cli.Exit("错误: --format 参数只能是 sql 或 json", 1)
+	}
+
+	return nil
+}
+
+// 数据比较参数验证
+func validateDiffFlags(c *cli.Context) error {
+	csv := c.String("csv")
+	if (csv == "") == (c.String("target-table") == "") {
+		return cli.Exit("错误: 必须且只能指定 --csv 或 --target-table 参数之一", 1)
+	}
+	if csv != "" {
+		if _, err := os.Stat(csv); err != nil {
+			return cli.Exit(fmt.Sprintf("错误: CSV文件 %s 不存在", csv), 1)
+		}
+	}
+
+	if c.Int("chunk-size") <= 0 {
+		return cli.Exit("错误: --chunk-size 参数必须大于0", 1)
+	}
+
+	switch c.String("format") {
+	case "csv", "json":
+	default:
+		return cli.Exit("错误: --format 参数只能是 csv 或 json", 1)
+	}
+
+	return nil
+}
+
+// 输出目录验证：目录非空时询问是否覆盖同名文件
+func validateOutDir(dir string) error {
+	if dir == "" {
+		return cli.Exit("错误: 必须指定 --out-dir 参数", 1)
+	}
+
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		fmt.Printf("警告: 目录 %s 非空，同名文件将被覆盖，是否继续? (y/N): ", dir)
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			return cli.Exit("操作已取消", 0)
+		}
+	}
+
+	return nil
+}
+
 // 导入参数验证
 func validateImportFlags(c *cli.Context) error {
 	table := c.String("table")
@@ -402,9 +1590,23 @@ func validateImportFlags(c *cli.Context) error {
 		return cli.Exit("错误: --batch 参数必须大于0", 1)
 	}
 
-	// 检查文件是否存在
-	if _, err := os.Stat(csv); os.IsNotExist(err) {
-		return cli.Exit(fmt.Sprintf("错误: CSV文件不存在: %s", csv), 1)
+	switch c.String("on-overflow") {
+	case importer.OverflowError, importer.OverflowTruncate:
+	case importer.OverflowReject:
+		if c.String("reject-file") == "" {
+			return cli.Exit("错误: --on-overflow reject 需要指定 --reject-file 参数", 1)
+		}
+	default:
+		return cli.Exit("错误: --on-overflow 参数只能是 error、truncate 或 reject", 1)
+	}
+
+	if !c.Bool("dry-run") && (c.Bool("strict") || c.String("report") != "") {
+		return cli.Exit("错误: --strict 和 --report 只能与 --dry-run 一起使用", 1)
+	}
+
+	// 检查文件是否存在（支持通配符和目录）
+	if _, err := importer.ExpandInputs(csv); err != nil {
+		return cli.Exit(fmt.Sprintf("错误: %v", err), 1)
 	}
 
 	return nil
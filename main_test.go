@@ -0,0 +1,76 @@
+// main_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mssql_ie/config"
+	"github.com/urfave/cli/v2"
+)
+
+// runTargetConfig 以 args 运行只包含目标连接参数的 copy 命令，返回源和目标连接配置
+func runTargetConfig(t *testing.T, args ...string) (config.DBConfig, config.DBConfig) {
+	t.Helper()
+	var source, target config.DBConfig
+	app := &cli.App{
+		Name:                      "mssql-ie",
+		Flags:                     globalFlags(),
+		DisableSliceFlagSeparator: true,
+		Commands: []*cli.Command{{
+			Name:  "copy",
+			Flags: targetConnFlags(),
+			Action: func(c *cli.Context) error {
+				var err error
+				if source, err = buildDBConfig(c); err != nil {
+					return err
+				}
+				target, err = buildTargetDBConfig(c)
+				return err
+			},
+		}},
+	}
+	if err := app.Run(append([]string{"mssql-ie"}, args...)); err != nil {
+		t.Fatalf("构建连接配置失败: %v", err)
+	}
+	return source, target
+}
+
+func TestTargetConfigDoesNotInheritSourceDSN(t *testing.T) {
+	profiles := filepath.Join(t.TempDir(), "profiles.toml")
+	data := "[dev]\nserver = \"dev-db\"\nuser = \"sa\"\npassword = \"secret\"\ndb = \"SalesDev\"\n"
+	if err := os.WriteFile(profiles, []byte(data), 0600); err != nil {
+		t.Fatalf("写入连接配置文件失败: %v", err)
+	}
+	sourceDSN := "server=prod-db;user id=report;password=pw;database=Sales;ApplicationIntent=ReadOnly;TrustServerCertificate=true"
+
+	t.Run("target-profile", func(t *testing.T) {
+		source, target := runTargetConfig(t, "--profile-file", profiles, "--dsn", sourceDSN,
+			"--conn-param", "failoverpartner=prod-db2",
+			"copy", "--target-profile", "dev", "--target-conn-param", "app name=etl")
+		if source.DSN == "" || source.Params["failoverpartner"] != "prod-db2" {
+			t.Fatalf("源连接配置 = %+v，期望保留 --dsn 和 --conn-param", source)
+		}
+		if target.DSN != "" {
+			t.Errorf("目标连接沿用了源连接字符串: %q", target.DSN)
+		}
+		if _, ok := target.Params["failoverpartner"]; ok || target.Params["app name"] != "etl" {
+			t.Errorf("目标连接参数 = %v，期望只包含 --target-conn-param", target.Params)
+		}
+		if target.Server != "dev-db" || target.DBName != "SalesDev" {
+			t.Errorf("目标连接 = %s/%s，期望 dev-db/SalesDev", target.Server, target.DBName)
+		}
+		if _, ok := source.Params["app name"]; ok {
+			t.Errorf("--target-conn-param 修改了源连接参数: %v", source.Params)
+		}
+	})
+
+	t.Run("target-dsn", func(t *testing.T) {
+		_, target := runTargetConfig(t, "--dsn", sourceDSN, "--conn-param", "failoverpartner=prod-db2",
+			"copy", "--target-dsn", "server=dev-db;user id=sa;password=pw;database=SalesDev")
+		if target.DSN != "server=dev-db;user id=sa;password=pw;database=SalesDev" || target.Params != nil {
+			t.Errorf("目标连接 DSN = %q，参数 = %v，期望只使用 --target-dsn", target.DSN, target.Params)
+		}
+	})
+}
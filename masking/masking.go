@@ -0,0 +1,175 @@
+// Package masking 实现导出时按列应用的数据脱敏规则
+// 哈希和伪造规则基于 HMAC-SHA256，同一密钥下相同的输入总是得到相同的输出，跨表关联关系得以保留
+package masking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rule 作用于单个列的脱敏规则
+type Rule struct {
+	Column string
+	Kind   string // hash, fake, partial, null
+	Arg    string
+	keep   int // partial 规则保留的末尾字符数
+	length int // hash 规则输出的十六进制字符数
+}
+
+// Rules 按列名（不区分大小写）索引的脱敏规则集合
+type Rules struct {
+	key   []byte
+	rules map[string]*Rule
+}
+
+// Parse 解析 --mask 参数，每个参数形如 列名=规则[:参数]
+// 支持的规则: hash[:长度]、fake[:name|email|phone]、partial:保留末尾字符数、null
+// hash 和 fake 规则需要提供密钥
+func Parse(specs []string, key string) (*Rules, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	r := &Rules{key: []byte(key), rules: map[string]*Rule{}}
+	needKey := false
+	for _, s := range specs {
+		column, spec, ok := strings.Cut(s, "=")
+		column = strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("无效的脱敏参数 %q，格式应为 列名=规则[:参数]", s)
+		}
+		kind, arg, _ := strings.Cut(strings.TrimSpace(spec), ":")
+		rule := &Rule{Column: column, Kind: strings.ToLower(kind), Arg: arg}
+		switch rule.Kind {
+		case "hash":
+			rule.length = 16
+			if arg != "" {
+				n, err := strconv.Atoi(arg)
+				if err != nil || n <= 0 || n > sha256.Size*2 {
+					return nil, fmt.Errorf("列 %s 的 hash 长度无效: %s（1-%d）", column, arg, sha256.Size*2)
+				}
+				rule.length = n
+			}
+			needKey = true
+		case "fake":
+			switch arg {
+			case "":
+				rule.Arg = "name"
+			case "name", "email", "phone":
+			default:
+				return nil, fmt.Errorf("列 %s 的 fake 类型无效: %s（name、email 或 phone）", column, arg)
+			}
+			needKey = true
+		case "partial":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("列 %s 的 partial 参数无效: %q，应为保留的末尾字符数", column, arg)
+			}
+			rule.keep = n
+		case "null":
+		default:
+			return nil, fmt.Errorf("列 %s 使用了未知的脱敏规则 %s，可用规则: hash, fake, partial, null", column, kind)
+		}
+		if prev, ok := r.rules[strings.ToLower(column)]; ok {
+			return nil, fmt.Errorf("列 %s 的脱敏规则重复指定（与 %s 相同）", column, prev.Column)
+		}
+		r.rules[strings.ToLower(column)] = rule
+	}
+
+	if needKey && key == "" {
+		return nil, fmt.Errorf("hash 和 fake 规则需要通过 --mask-key 或环境变量 MSSQL_MASK_KEY 指定密钥")
+	}
+	return r, nil
+}
+
+// For 返回指定列的脱敏规则，未配置时返回 nil
+func (r *Rules) For(column string) *Rule {
+	if r == nil {
+		return nil
+	}
+	return r.rules[strings.ToLower(column)]
+}
+
+// Check 检查所有配置了脱敏规则的列都存在于 columns 中
+// 脱敏列缺失意味着敏感数据可能以未脱敏的形式导出，因此必须报错
+func (r *Rules) Check(columns []string) error {
+	if r == nil {
+		return nil
+	}
+	known := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		known[strings.ToLower(c)] = true
+	}
+	var missing []string
+	for key, rule := range r.rules {
+		if !known[key] {
+			missing = append(missing, rule.Column)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("脱敏规则中的列不存在: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Apply 对值应用脱敏规则，空值（NULL）保持为空
+func (r *Rules) Apply(rule *Rule, value string) string {
+	if rule == nil || value == "" {
+		return value
+	}
+	switch rule.Kind {
+	case "hash":
+		return hex.EncodeToString(r.sum(value))[:rule.length]
+	case "fake":
+		return r.fake(rule.Arg, value)
+	case "partial":
+		// 值不长于保留位数时仍至少替换一个字符，避免短值原样导出
+		runes := []rune(value)
+		keep := min(rule.keep, len(runes)-1)
+		return strings.Repeat("*", len(runes)-keep) + string(runes[len(runes)-keep:])
+	default: // null
+		return ""
+	}
+}
+
+// sum 计算值的 HMAC-SHA256
+func (r *Rules) sum(value string) []byte {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+var (
+	fakeSurnames = []string{"王", "李", "张", "刘", "陈", "杨", "黄", "赵", "吴", "周",
+		"徐", "孙", "马", "朱", "胡", "郭", "何", "高", "林", "罗"}
+	fakeGivenNames = []string{"伟", "芳", "娜", "敏", "静", "丽", "强", "磊", "军", "洋",
+		"勇", "艳", "杰", "娟", "涛", "明", "超", "秀英", "霞", "平", "刚", "桂英", "华", "玉兰"}
+)
+
+// fake 根据值的哈希生成格式合理的伪造值
+func (r *Rules) fake(kind, value string) string {
+	sum := r.sum(value)
+	n := binary.BigEndian.Uint64(sum[:8])
+	switch kind {
+	case "email":
+		return "user_" + hex.EncodeToString(sum[:5]) + "@example.com"
+	case "phone":
+		// 保持原值的长度，全部替换为数字
+		digits := make([]byte, utf8.RuneCountInString(value))
+		for i := range digits {
+			digits[i] = '0' + sum[i%len(sum)]%10
+		}
+		return string(digits)
+	default: // name
+		return fakeSurnames[n%uint64(len(fakeSurnames))] +
+			fakeGivenNames[(n/uint64(len(fakeSurnames)))%uint64(len(fakeGivenNames))]
+	}
+}
@@ -0,0 +1,126 @@
+// masking/masking_test.go
+package masking
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// mustParse 解析脱敏规则，失败时终止测试
+func mustParse(t *testing.T, key string, specs ...string) *Rules {
+	t.Helper()
+	r, err := Parse(specs, key)
+	if err != nil {
+		t.Fatalf("解析脱敏规则失败: %v", err)
+	}
+	return r
+}
+
+func TestHashDeterministic(t *testing.T) {
+	r := mustParse(t, "k1", "email=hash", "id=hash:8")
+	a := r.Apply(r.For("email"), "alice@example.com")
+	b := r.Apply(r.For("EMAIL"), "alice@example.com")
+	if a != b {
+		t.Errorf("相同输入的哈希不一致: %s != %s", a, b)
+	}
+	if len(a) != 16 || !regexp.MustCompile(`^[0-9a-f]+$`).MatchString(a) {
+		t.Errorf("默认哈希 = %q，期望 16 位十六进制", a)
+	}
+	if got := r.Apply(r.For("id"), "alice@example.com"); got != a[:8] {
+		t.Errorf("hash:8 = %q，期望 %q", got, a[:8])
+	}
+	if r.Apply(r.For("email"), "bob@example.com") == a {
+		t.Error("不同输入得到相同的哈希")
+	}
+
+	other := mustParse(t, "k2", "email=hash")
+	if other.Apply(other.For("email"), "alice@example.com") == a {
+		t.Error("不同密钥得到相同的哈希")
+	}
+}
+
+func TestFake(t *testing.T) {
+	r := mustParse(t, "k1", "name=fake", "email=fake:email", "phone=fake:phone")
+	tests := []struct {
+		column  string
+		value   string
+		pattern string
+	}{
+		{"name", "张三", `^\p{Han}{2,3}$`},
+		{"email", "alice@corp.com", `^user_[0-9a-f]{10}@example\.com$`},
+		{"phone", "13800138000", `^[0-9]{11}$`},
+		{"phone", "+86 21-1234", `^[0-9]{11}$`},
+	}
+	for _, tt := range tests {
+		got := r.Apply(r.For(tt.column), tt.value)
+		if !regexp.MustCompile(tt.pattern).MatchString(got) {
+			t.Errorf("fake %s(%q) = %q，不匹配 %s", tt.column, tt.value, got, tt.pattern)
+		}
+		if again := r.Apply(r.For(tt.column), tt.value); again != got {
+			t.Errorf("fake %s(%q) 两次结果不同: %q != %q", tt.column, tt.value, got, again)
+		}
+	}
+}
+
+func TestPartial(t *testing.T) {
+	r := mustParse(t, "", "phone=partial:4", "code=partial:0")
+	tests := []struct {
+		column string
+		value  string
+		want   string
+	}{
+		{"phone", "13800138000", "*******8000"},
+		{"phone", "12345", "*2345"},
+		{"phone", "1234", "*234"},
+		{"phone", "12", "*2"},
+		{"phone", "1", "*"},
+		{"phone", "电话号码五", "*话号码五"},
+		{"code", "abc", "***"},
+	}
+	for _, tt := range tests {
+		if got := r.Apply(r.For(tt.column), tt.value); got != tt.want {
+			t.Errorf("partial %s(%q) = %q，期望 %q", tt.column, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEmptyPassthrough(t *testing.T) {
+	r := mustParse(t, "k1", "a=hash", "b=fake", "c=partial:2", "d=null")
+	for _, col := range []string{"a", "b", "c", "d"} {
+		if got := r.Apply(r.For(col), ""); got != "" {
+			t.Errorf("列 %s 的空值（NULL）被替换为 %q", col, got)
+		}
+	}
+	if got := r.Apply(r.For("d"), "secret"); got != "" {
+		t.Errorf("null 规则 = %q，期望为空", got)
+	}
+	if got := r.Apply(r.For("missing"), "value"); got != "value" {
+		t.Errorf("未配置规则的列 = %q，期望原值", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		key     string
+		wantErr string
+	}{
+		{"缺少密钥", []string{"email=hash"}, "", "密钥"},
+		{"未知规则", []string{"email=encrypt"}, "k", "未知的脱敏规则"},
+		{"hash 长度过大", []string{"email=hash:65"}, "k", "hash 长度无效"},
+		{"partial 缺少参数", []string{"phone=partial"}, "", "partial 参数无效"},
+		{"fake 类型无效", []string{"name=fake:address"}, "k", "fake 类型无效"},
+		{"格式错误", []string{"=hash"}, "k", "格式应为"},
+		{"重复列", []string{"email=hash", "Email=null"}, "k", "重复指定"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.specs, tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v，期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// profile/parse.go
+package profile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// value 配置项的值
+type value struct {
+	str    string
+	i      int
+	b      bool
+	isStr  bool
+	isInt  bool
+	isBool bool
+}
+
+// parse 解析连接配置文件
+// 支持 TOML 的常用子集：[表名] 分组、key = value 键值对、# 注释，
+// 值可以是基本字符串（"..."，支持常用转义）、字面量字符串（'...'）、整数和布尔值
+func parse(data []byte) (map[string]map[string]value, error) {
+	tables := map[string]map[string]value{"": {}}
+	current := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("第%d行: 无效的表头 %s", lineNum, line)
+			}
+			current = unquoteKey(strings.TrimSpace(line[1 : len(line)-1]))
+			if current == "" {
+				return nil, fmt.Errorf("第%d行: 表名不能为空", lineNum)
+			}
+			if _, ok := tables[current]; ok {
+				return nil, fmt.Errorf("第%d行: 重复定义的表 %s", lineNum, current)
+			}
+			tables[current] = map[string]value{}
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("第%d行: 应为 key = value 格式", lineNum)
+		}
+		key = strings.ToLower(unquoteKey(strings.TrimSpace(key)))
+		v, err := parseValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("第%d行: %w", lineNum, err)
+		}
+		if _, ok := tables[current][key]; ok {
+			return nil, fmt.Errorf("第%d行: 重复定义的键 %s", lineNum, key)
+		}
+		tables[current][key] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// stripComment 去除行内 # 注释，忽略字符串中的 #
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquoteKey 去除键名或表名的引号
+func unquoteKey(key string) string {
+	if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') && key[len(key)-1] == key[0] {
+		return key[1 : len(key)-1]
+	}
+	return key
+}
+
+// parseValue 解析值
+func parseValue(raw string) (value, error) {
+	switch {
+	case raw == "":
+		return value{}, fmt.Errorf("值不能为空")
+	case strings.HasPrefix(raw, `"`):
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return value{}, fmt.Errorf("无效的字符串 %s", raw)
+		}
+		return value{str: s, isStr: true}, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return value{}, fmt.Errorf("无效的字符串 %s", raw)
+		}
+		return value{str: raw[1 : len(raw)-1], isStr: true}, nil
+	case raw == "true" || raw == "false":
+		return value{b: raw == "true", isBool: true}, nil
+	default:
+		n, err := strconv.Atoi(strings.ReplaceAll(raw, "_", ""))
+		if err != nil {
+			return value{}, fmt.Errorf("不支持的值 %s（仅支持字符串、整数和布尔值）", raw)
+		}
+		return value{i: n, isInt: true}, nil
+	}
+}
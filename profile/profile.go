@@ -0,0 +1,177 @@
+// Package profile 读取连接配置文件中的命名连接配置
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Profile 命名的连接配置，未设置的字段为零值
+type Profile struct {
+	Name     string
+	Server   string
+	Port     int
+	User     string
+	Password string
+	// PasswordEnv 从指定环境变量读取密码
+	PasswordEnv string
+	// PasswordFile 从指定文件读取密码（去除首尾空白）
+	PasswordFile string
+	// PasswordCmd 执行命令并使用其标准输出作为密码（如 pass show db/prod）
+	PasswordCmd string
+	DB          string
+	Encrypt     string
+	Charset     string // 已弃用，仅为兼容旧配置文件而保留，不起作用
+	Timeout     int
+}
+
+// DefaultPath 返回默认的配置文件路径 ~/.config/mssql-ie/profiles.toml（所有平台相同），
+// 设置了 XDG_CONFIG_HOME 时为 $XDG_CONFIG_HOME/mssql-ie/profiles.toml
+func DefaultPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" || !filepath.IsAbs(dir) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "mssql-ie", "profiles.toml")
+}
+
+// Load 从配置文件读取指定名称的连接配置
+func Load(path, name string) (*Profile, error) {
+	if path == "" {
+		return nil, fmt.Errorf("无法确定连接配置文件路径，请通过 --profile-file 指定")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取连接配置文件失败: %w", err)
+	}
+	tables, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析连接配置文件 %s 失败: %w", path, err)
+	}
+
+	values, ok := tables[name]
+	if !ok {
+		// 兼容 [profiles.name] 写法
+		values, ok = tables["profiles."+name]
+	}
+	if !ok {
+		var names []string
+		for n := range tables {
+			if n != "" {
+				names = append(names, strings.TrimPrefix(n, "profiles."))
+			}
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("连接配置 %s 不存在，可用配置: %s", name, strings.Join(names, ", "))
+	}
+
+	p := &Profile{Name: name}
+	for key, v := range values {
+		if err := p.set(key, v); err != nil {
+			return nil, fmt.Errorf("连接配置 %s: %w", name, err)
+		}
+	}
+	return p, nil
+}
+
+// set 设置配置项，字符串和整数类型不匹配时报错
+func (p *Profile) set(key string, v value) error {
+	strFields := map[string]*string{
+		"server":        &p.Server,
+		"user":          &p.User,
+		"password":      &p.Password,
+		"password_env":  &p.PasswordEnv,
+		"password_file": &p.PasswordFile,
+		"password_cmd":  &p.PasswordCmd,
+		"db":            &p.DB,
+		"database":      &p.DB,
+		"encrypt":       &p.Encrypt,
+		"charset":       &p.Charset,
+	}
+	intFields := map[string]*int{
+		"port":    &p.Port,
+		"timeout": &p.Timeout,
+	}
+
+	if f, ok := strFields[key]; ok {
+		switch {
+		case v.isStr:
+			*f = v.str
+		case key == "encrypt" && v.isBool:
+			// encrypt = true/false 与 --encrypt 的取值对应
+			*f = map[bool]string{true: "required", false: "off"}[v.b]
+		default:
+			return fmt.Errorf("%s 必须是字符串", key)
+		}
+		return nil
+	}
+	if f, ok := intFields[key]; ok {
+		if !v.isInt {
+			return fmt.Errorf("%s 必须是整数", key)
+		}
+		*f = v.i
+		return nil
+	}
+	return fmt.Errorf("未知的配置项 %s", key)
+}
+
+// ResolvePassword 按 password、password_env、password_file、password_cmd 的顺序解析密码
+func (p *Profile) ResolvePassword() (string, error) {
+	switch {
+	case p.Password != "":
+		return p.Password, nil
+	case p.PasswordEnv != "":
+		pw := os.Getenv(p.PasswordEnv)
+		if pw == "" {
+			return "", fmt.Errorf("连接配置 %s 指定的环境变量 %s 为空", p.Name, p.PasswordEnv)
+		}
+		return pw, nil
+	case p.PasswordFile != "":
+		data, err := os.ReadFile(expandHome(p.PasswordFile))
+		if err != nil {
+			return "", fmt.Errorf("读取连接配置 %s 的密码文件失败: %w", p.Name, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case p.PasswordCmd != "":
+		return runPasswordCmd(p.Name, p.PasswordCmd)
+	}
+	return "", nil
+}
+
+// runPasswordCmd 通过系统 shell 执行命令获取密码
+func runPasswordCmd(name, command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("执行连接配置 %s 的 password_cmd 失败: %w %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// expandHome 展开路径开头的 ~
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
@@ -0,0 +1,128 @@
+// Package retry 识别 SQL Server 的暂时性错误，并按退避策略重试
+package retry
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+
+	"github.com/mssql_ie/config"
+)
+
+// maxBackoff 单次等待时间上限
+const maxBackoff = 30 * time.Second
+
+// transientErrors 可重试的 SQL Server 错误号
+var transientErrors = map[int32]bool{
+	1205:  true, // 死锁牺牲品
+	1222:  true, // 锁请求超时
+	3960:  true, // 快照隔离更新冲突
+	4060:  true, // 无法打开登录请求的数据库（数据库正在启动或故障转移中）
+	4221:  true, // 可读辅助副本登录等待超时
+	10053: true, // 连接被主机中止
+	10054: true, // 连接被远程主机重置
+	10060: true, // 连接超时
+	10928: true, // 资源限制
+	10929: true, // 资源限制
+	40143: true, // 服务处理请求时出错
+	40197: true, // 服务处理请求时出错（通常为升级或故障转移）
+	40501: true, // 服务繁忙
+	40613: true, // 数据库当前不可用
+	41301: true, // 内存优化表依赖提交失败
+	41302: true, // 内存优化表更新冲突
+	41305: true, // 内存优化表可重复读验证失败
+	41325: true, // 内存优化表可序列化验证失败
+	49918: true, // 资源不足
+	49919: true, // 资源不足
+	49920: true, // 服务繁忙
+	233:   true, // 连接已建立但登录时出错
+	64:    true, // 指定的网络名不再可用
+	121:   true, // 信号灯超时
+}
+
+// IsTransient 判断错误是否为暂时性错误（死锁、故障转移、资源限制、连接中断等）
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	var sqlErr mssql.Error
+	if errors.As(err, &sqlErr) {
+		for _, e := range sqlErr.All {
+			if transientErrors[e.Number] {
+				return true
+			}
+		}
+		return transientErrors[sqlErr.Number]
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	// 主机名不存在属于配置错误，不重试
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var streamErr mssql.StreamError
+	return errors.As(err, &streamErr)
+}
+
+// IsServerError 判断错误是否由服务器返回（而非连接中断），此时服务器已明确拒绝请求
+func IsServerError(err error) bool {
+	var sqlErr mssql.Error
+	return errors.As(err, &sqlErr)
+}
+
+// Backoff 返回第 attempt 次重试前的等待时间，每次翻倍，不超过 30 秒
+func Backoff(cfg config.RetryConfig, attempt int) time.Duration {
+	d := cfg.Backoff
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// Retry 在 err 为暂时性错误时等待退避时间后重新执行 fn，最多重试 cfg.Retries 次
+// what 描述被重试的操作，用于输出重试信息；返回最后一次执行的错误
+func Retry(ctx context.Context, cfg config.RetryConfig, what string, err error, fn func() error) error {
+	return retryIf(ctx, cfg, what, err, fn, IsTransient)
+}
+
+// retryIf 在 retryable(err) 为 true 时按退避策略重新执行 fn
+func retryIf(ctx context.Context, cfg config.RetryConfig, what string, err error, fn func() error, retryable func(error) bool) error {
+	for attempt := 1; err != nil && attempt <= cfg.Retries && retryable(err); attempt++ {
+		wait := Backoff(cfg, attempt)
+		fmt.Printf("⚠️  %s遇到暂时性错误，%v 后第 %d/%d 次重试: %v\n", what, wait, attempt, cfg.Retries, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		err = fn()
+	}
+	return err
+}
+
+// Do 执行 fn，遇到暂时性错误时按 cfg 重试
+func Do(ctx context.Context, cfg config.RetryConfig, what string, fn func() error) error {
+	return Retry(ctx, cfg, what, fn(), fn)
+}
+
+// DoOnConn 在同一连接上执行 fn，只重试服务器返回的暂时性错误（死锁、快照冲突等）
+// 连接中断时会话状态（临时表、会话设置）已丢失，在同一连接上重试没有意义，直接返回错误
+func DoOnConn(ctx context.Context, cfg config.RetryConfig, what string, fn func() error) error {
+	return retryIf(ctx, cfg, what, fn(), fn, func(err error) bool {
+		return IsServerError(err) && IsTransient(err)
+	})
+}
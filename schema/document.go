@@ -0,0 +1,105 @@
+// schema/document.go
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// DocumentVersion JSON 表结构文档的格式版本
+const DocumentVersion = 1
+
+// Document JSON 表结构文档，可被其他命令读取（如 copy --schema-file 按文档建表）
+type Document struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Database  string    `json:"database,omitempty"`
+	Tables    []*Table  `json:"tables"`
+}
+
+// NewDocument 创建表结构文档
+func NewDocument(database string, tables []*Table) *Document {
+	return &Document{
+		Version:   DocumentVersion,
+		CreatedAt: time.Now(),
+		Database:  database,
+		Tables:    tables,
+	}
+}
+
+// LoadTables 依次读取多张表的结构，names 为 schema/table 对
+func LoadTables(ctx context.Context, db queryer, names [][2]string) ([]*Table, error) {
+	tables := make([]*Table, 0, len(names))
+	for _, n := range names {
+		t, err := LoadTable(ctx, db, n[0], n[1])
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// WriteJSON 以 JSON 格式输出表结构文档
+func (d *Document) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return fmt.Errorf("输出JSON表结构失败: %w", err)
+	}
+	return nil
+}
+
+// WriteSQL 以 T-SQL 脚本输出表结构
+// 先输出所有表的建表与索引语句，再输出外键，保证脚本按顺序执行时引用的表已存在
+func (d *Document) WriteSQL(w io.Writer) error {
+	var b strings.Builder
+	for _, t := range d.Tables {
+		fmt.Fprintf(&b, "-- 表 %s\n", t.QuotedName())
+		b.WriteString(t.CreateSQL() + "\nGO\n")
+		for _, stmt := range t.IndexSQL() {
+			b.WriteString(stmt + "\nGO\n")
+		}
+		b.WriteString("\n")
+	}
+	for _, t := range d.Tables {
+		for _, stmt := range t.ForeignKeySQL() {
+			b.WriteString(stmt + "\nGO\n")
+		}
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("输出SQL表结构失败: %w", err)
+	}
+	return nil
+}
+
+// ReadDocument 读取 JSON 表结构文档
+func ReadDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取表结构文档失败: %w", err)
+	}
+	var d Document
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("解析表结构文档失败: %w", err)
+	}
+	if d.Version != DocumentVersion {
+		return nil, fmt.Errorf("不支持的表结构文档版本: %d", d.Version)
+	}
+	return &d, nil
+}
+
+// Table 按架构名和表名（不区分大小写）查找文档中的表，不存在时返回 nil
+func (d *Document) Table(schemaName, tableName string) *Table {
+	for _, t := range d.Tables {
+		if strings.EqualFold(t.Schema, schemaName) && strings.EqualFold(t.Name, tableName) {
+			return t
+		}
+	}
+	return nil
+}
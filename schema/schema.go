@@ -0,0 +1,482 @@
+// Package schema 从系统目录视图重建表结构（DDL）
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mssql_ie/utils"
+)
+
+// Table 表结构
+type Table struct {
+	Schema      string       `json:"schema"`
+	Name        string       `json:"name"`
+	Columns     []Column     `json:"columns"`
+	PrimaryKey  *Index       `json:"primary_key,omitempty"`
+	Indexes     []Index      `json:"indexes,omitempty"`
+	ForeignKeys []ForeignKey `json:"foreign_keys,omitempty"`
+	Checks      []Check      `json:"checks,omitempty"`
+}
+
+// Column 列定义
+type Column struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	MaxLength int       `json:"max_length"` // 字节数，-1 表示 MAX
+	Precision int       `json:"precision"`
+	Scale     int       `json:"scale"`
+	Nullable  bool      `json:"nullable"`
+	Identity  *Identity `json:"identity,omitempty"`
+	Collation string    `json:"collation,omitempty"`
+	Computed  *Computed `json:"computed,omitempty"`
+	Default   *Default  `json:"default,omitempty"`
+}
+
+// Computed 计算列定义
+type Computed struct {
+	Definition string `json:"definition"`
+	Persisted  bool   `json:"persisted,omitempty"`
+}
+
+// Default 默认值约束
+type Default struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+// Check 检查约束
+type Check struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+// Identity 自增列定义
+type Identity struct {
+	Seed      int64 `json:"seed"`
+	Increment int64 `json:"increment"`
+}
+
+// Index 索引或主键/唯一约束
+type Index struct {
+	Name             string        `json:"name"`
+	Clustered        bool          `json:"clustered"`
+	Unique           bool          `json:"unique"`
+	UniqueConstraint bool          `json:"unique_constraint,omitempty"`
+	Columns          []IndexColumn `json:"columns"`
+	Included         []string      `json:"included,omitempty"`
+	Filter           string        `json:"filter,omitempty"`
+}
+
+// IndexColumn 索引键列
+type IndexColumn struct {
+	Name       string `json:"name"`
+	Descending bool   `json:"descending,omitempty"`
+}
+
+// ForeignKey 外键约束
+type ForeignKey struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedSchema  string   `json:"referenced_schema"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnDelete          string   `json:"on_delete,omitempty"`
+	OnUpdate          string   `json:"on_update,omitempty"`
+}
+
+// queryer 抽象 *sql.DB、*sql.Conn 与 *sql.Tx 共有的查询方法
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// QuotedName 返回转义后的 [schema].[table] 形式的表名
+func (t *Table) QuotedName() string {
+	return utils.EscapeIdentifier(t.Schema) + "." + utils.EscapeIdentifier(t.Name)
+}
+
+// HasIdentity 判断表是否包含自增列
+func (t *Table) HasIdentity() bool {
+	for _, c := range t.Columns {
+		if c.Identity != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Insertable 判断列是否可以显式插入值（计算列和 rowversion 列不可插入）
+func (c Column) Insertable() bool {
+	t := strings.ToLower(c.Type)
+	return c.Computed == nil && t != "timestamp" && t != "rowversion"
+}
+
+// LoadTable 从 sys.columns、sys.types、sys.indexes、sys.foreign_keys、
+// sys.check_constraints 与 sys.default_constraints 读取表结构
+func LoadTable(ctx context.Context, db queryer, schemaName, tableName string) (*Table, error) {
+	t := &Table{Schema: schemaName, Name: tableName}
+	objectName := t.QuotedName()
+
+	if err := loadColumns(ctx, db, t, objectName); err != nil {
+		return nil, fmt.Errorf("读取表 %s 的列定义失败: %w", objectName, err)
+	}
+	if len(t.Columns) == 0 {
+		return nil, fmt.Errorf("表 %s 不存在或没有列", objectName)
+	}
+	if err := loadIndexes(ctx, db, t, objectName); err != nil {
+		return nil, fmt.Errorf("读取表 %s 的索引失败: %w", objectName, err)
+	}
+	if err := loadForeignKeys(ctx, db, t, objectName); err != nil {
+		return nil, fmt.Errorf("读取表 %s 的外键失败: %w", objectName, err)
+	}
+	if err := loadChecks(ctx, db, t, objectName); err != nil {
+		return nil, fmt.Errorf("读取表 %s 的检查约束失败: %w", objectName, err)
+	}
+	return t, nil
+}
+
+// loadColumns 读取列定义
+func loadColumns(ctx context.Context, db queryer, t *Table, objectName string) error {
+	rows, err := db.QueryContext(ctx, `
+		/* mssql_ie tool query for table columns*/
+		SELECT c.name,
+			CASE WHEN ty.is_user_defined = 1
+				THEN QUOTENAME(SCHEMA_NAME(ty.schema_id)) + '.' + QUOTENAME(ty.name)
+				ELSE ty.name END,
+			c.max_length, c.precision, c.scale, c.is_nullable, c.is_identity,
+			CAST(ISNULL(ic.seed_value, 0) AS BIGINT),
+			CAST(ISNULL(ic.increment_value, 0) AS BIGINT),
+			ISNULL(c.collation_name, ''), c.is_computed,
+			ISNULL(cc.definition, ''), ISNULL(cc.is_persisted, 0),
+			ISNULL(dc.name, ''), ISNULL(dc.definition, '')
+		FROM sys.columns c
+		JOIN sys.types ty ON ty.user_type_id = c.user_type_id
+		LEFT JOIN sys.identity_columns ic ON ic.object_id = c.object_id AND ic.column_id = c.column_id
+		LEFT JOIN sys.computed_columns cc ON cc.object_id = c.object_id AND cc.column_id = c.column_id
+		LEFT JOIN sys.default_constraints dc ON dc.object_id = c.default_object_id
+		WHERE c.object_id = OBJECT_ID(?)
+		ORDER BY c.column_id
+	`, objectName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var col Column
+		var isIdentity, isComputed, persisted bool
+		var seed, increment int64
+		var computedDef, defaultName, defaultDef string
+		if err := rows.Scan(&col.Name, &col.Type, &col.MaxLength, &col.Precision, &col.Scale,
+			&col.Nullable, &isIdentity, &seed, &increment, &col.Collation, &isComputed,
+			&computedDef, &persisted, &defaultName, &defaultDef); err != nil {
+			return err
+		}
+		if isIdentity {
+			col.Identity = &Identity{Seed: seed, Increment: increment}
+		}
+		if isComputed {
+			col.Computed = &Computed{Definition: computedDef, Persisted: persisted}
+			col.Collation = ""
+		}
+		if defaultName != "" {
+			col.Default = &Default{Name: defaultName, Definition: defaultDef}
+		}
+		t.Columns = append(t.Columns, col)
+	}
+	return rows.Err()
+}
+
+// loadIndexes 读取主键、唯一约束和普通索引（仅聚集/非聚集行存储索引）
+func loadIndexes(ctx context.Context, db queryer, t *Table, objectName string) error {
+	rows, err := db.QueryContext(ctx, `
+		/* mssql_ie tool query for table indexes*/
+		SELECT i.index_id, i.name, i.type, i.is_primary_key, i.is_unique, i.is_unique_constraint,
+			ISNULL(i.filter_definition, ''), c.name, ic.is_descending_key, ic.is_included_column
+		FROM sys.indexes i
+		JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE i.object_id = OBJECT_ID(?) AND i.type IN (1, 2) AND i.is_hypothetical = 0
+		ORDER BY i.index_id, ic.is_included_column, ic.key_ordinal, ic.index_column_id
+	`, objectName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var current *Index
+	var currentID int
+	var currentPK bool
+	flush := func() {
+		if current == nil {
+			return
+		}
+		if currentPK {
+			t.PrimaryKey = current
+		} else {
+			t.Indexes = append(t.Indexes, *current)
+		}
+	}
+	for rows.Next() {
+		var id, typ int
+		var name, filter, colName string
+		var isPK, isUnique, isUniqueConstraint, desc, included bool
+		if err := rows.Scan(&id, &name, &typ, &isPK, &isUnique, &isUniqueConstraint,
+			&filter, &colName, &desc, &included); err != nil {
+			return err
+		}
+		if current == nil || id != currentID {
+			flush()
+			current = &Index{
+				Name:             name,
+				Clustered:        typ == 1,
+				Unique:           isUnique,
+				UniqueConstraint: isUniqueConstraint,
+				Filter:           filter,
+			}
+			currentID = id
+			currentPK = isPK
+		}
+		if included {
+			current.Included = append(current.Included, colName)
+		} else {
+			current.Columns = append(current.Columns, IndexColumn{Name: colName, Descending: desc})
+		}
+	}
+	flush()
+	return rows.Err()
+}
+
+// loadForeignKeys 读取外键约束
+func loadForeignKeys(ctx context.Context, db queryer, t *Table, objectName string) error {
+	rows, err := db.QueryContext(ctx, `
+		/* mssql_ie tool query for table foreign keys*/
+		SELECT fk.name, SCHEMA_NAME(rt.schema_id), rt.name, pc.name, rc.name,
+			fk.delete_referential_action_desc, fk.update_referential_action_desc
+		FROM sys.foreign_keys fk
+		JOIN sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id
+		JOIN sys.tables rt ON rt.object_id = fk.referenced_object_id
+		JOIN sys.columns pc ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id
+		JOIN sys.columns rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+		WHERE fk.parent_object_id = OBJECT_ID(?)
+		ORDER BY fk.name, fkc.constraint_column_id
+	`, objectName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, refSchema, refTable, col, refCol, onDelete, onUpdate string
+		if err := rows.Scan(&name, &refSchema, &refTable, &col, &refCol, &onDelete, &onUpdate); err != nil {
+			return err
+		}
+		n := len(t.ForeignKeys)
+		if n == 0 || t.ForeignKeys[n-1].Name != name {
+			t.ForeignKeys = append(t.ForeignKeys, ForeignKey{
+				Name:             name,
+				ReferencedSchema: refSchema,
+				ReferencedTable:  refTable,
+				OnDelete:         referentialAction(onDelete),
+				OnUpdate:         referentialAction(onUpdate),
+			})
+			n++
+		}
+		fk := &t.ForeignKeys[n-1]
+		fk.Columns = append(fk.Columns, col)
+		fk.ReferencedColumns = append(fk.ReferencedColumns, refCol)
+	}
+	return rows.Err()
+}
+
+// loadChecks 读取检查约束
+func loadChecks(ctx context.Context, db queryer, t *Table, objectName string) error {
+	rows, err := db.QueryContext(ctx, `
+		/* mssql_ie tool query for table check constraints*/
+		SELECT name, definition
+		FROM sys.check_constraints
+		WHERE parent_object_id = OBJECT_ID(?)
+		ORDER BY name
+	`, objectName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c Check
+		if err := rows.Scan(&c.Name, &c.Definition); err != nil {
+			return err
+		}
+		t.Checks = append(t.Checks, c)
+	}
+	return rows.Err()
+}
+
+// referentialAction 将 NO_ACTION、SET_NULL 等转换为T-SQL语法，NO ACTION 返回空字符串
+func referentialAction(desc string) string {
+	action := strings.ReplaceAll(desc, "_", " ")
+	if action == "NO ACTION" {
+		return ""
+	}
+	return action
+}
+
+// TypeSQL 返回列的T-SQL类型声明，如 nvarchar(50)、decimal(18,2)
+func (c Column) TypeSQL() string {
+	switch strings.ToLower(c.Type) {
+	case "varchar", "char", "varbinary", "binary":
+		return c.Type + "(" + lengthSQL(c.MaxLength) + ")"
+	case "nvarchar", "nchar":
+		if c.MaxLength == -1 {
+			return c.Type + "(MAX)"
+		}
+		return c.Type + "(" + lengthSQL(c.MaxLength/2) + ")"
+	case "decimal", "numeric":
+		return fmt.Sprintf("%s(%d,%d)", c.Type, c.Precision, c.Scale)
+	case "datetime2", "time", "datetimeoffset":
+		return fmt.Sprintf("%s(%d)", c.Type, c.Scale)
+	case "float":
+		return fmt.Sprintf("%s(%d)", c.Type, c.Precision)
+	default:
+		return c.Type
+	}
+}
+
+// lengthSQL 返回长度声明，-1 表示 MAX
+func lengthSQL(n int) string {
+	if n == -1 {
+		return "MAX"
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+// ColumnSQL 返回列定义，如 [name] nvarchar(50) COLLATE Chinese_PRC_CI_AS NOT NULL
+func (c Column) ColumnSQL() string {
+	name := utils.EscapeIdentifier(c.Name)
+	if c.Computed != nil {
+		def := fmt.Sprintf("%s AS %s", name, c.Computed.Definition)
+		if c.Computed.Persisted {
+			def += " PERSISTED"
+		}
+		return def
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", name, c.TypeSQL())
+	if c.Collation != "" {
+		b.WriteString(" COLLATE " + c.Collation)
+	}
+	if c.Identity != nil {
+		fmt.Fprintf(&b, " IDENTITY(%d,%d)", c.Identity.Seed, c.Identity.Increment)
+	}
+	if c.Nullable {
+		b.WriteString(" NULL")
+	} else {
+		b.WriteString(" NOT NULL")
+	}
+	if c.Default != nil {
+		fmt.Fprintf(&b, " CONSTRAINT %s DEFAULT %s", utils.EscapeIdentifier(c.Default.Name), c.Default.Definition)
+	}
+	return b.String()
+}
+
+// CreateSQL 生成包含主键、默认值与检查约束的 CREATE TABLE 语句
+func (t *Table) CreateSQL() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n", t.QuotedName())
+	for i, c := range t.Columns {
+		if i > 0 {
+			b.WriteString(",\n")
+		}
+		b.WriteString("\t" + c.ColumnSQL())
+	}
+	if pk := t.PrimaryKey; pk != nil {
+		fmt.Fprintf(&b, ",\n\tCONSTRAINT %s PRIMARY KEY %s (%s)",
+			utils.EscapeIdentifier(pk.Name), clusteredSQL(pk.Clustered), indexColumnsSQL(pk.Columns))
+	}
+	for _, ck := range t.Checks {
+		fmt.Fprintf(&b, ",\n\tCONSTRAINT %s CHECK %s", utils.EscapeIdentifier(ck.Name), ck.Definition)
+	}
+	b.WriteString("\n)")
+	return b.String()
+}
+
+// IndexSQL 生成唯一约束与索引的创建语句
+func (t *Table) IndexSQL() []string {
+	var stmts []string
+	for _, idx := range t.Indexes {
+		if idx.UniqueConstraint {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE %s (%s)",
+				t.QuotedName(), utils.EscapeIdentifier(idx.Name), clusteredSQL(idx.Clustered), indexColumnsSQL(idx.Columns)))
+			continue
+		}
+
+		var b strings.Builder
+		b.WriteString("CREATE ")
+		if idx.Unique {
+			b.WriteString("UNIQUE ")
+		}
+		fmt.Fprintf(&b, "%s INDEX %s ON %s (%s)", clusteredSQL(idx.Clustered),
+			utils.EscapeIdentifier(idx.Name), t.QuotedName(), indexColumnsSQL(idx.Columns))
+		if len(idx.Included) > 0 {
+			fmt.Fprintf(&b, " INCLUDE (%s)", identifierList(idx.Included))
+		}
+		if idx.Filter != "" {
+			fmt.Fprintf(&b, " WHERE %s", idx.Filter)
+		}
+		stmts = append(stmts, b.String())
+	}
+	return stmts
+}
+
+// ForeignKeySQL 生成外键约束的创建语句
+func (t *Table) ForeignKeySQL() []string {
+	var stmts []string
+	for _, fk := range t.ForeignKeys {
+		var b strings.Builder
+		fmt.Fprintf(&b, "ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s.%s (%s)",
+			t.QuotedName(), utils.EscapeIdentifier(fk.Name), identifierList(fk.Columns),
+			utils.EscapeIdentifier(fk.ReferencedSchema), utils.EscapeIdentifier(fk.ReferencedTable),
+			identifierList(fk.ReferencedColumns))
+		if fk.OnDelete != "" {
+			b.WriteString(" ON DELETE " + fk.OnDelete)
+		}
+		if fk.OnUpdate != "" {
+			b.WriteString(" ON UPDATE " + fk.OnUpdate)
+		}
+		stmts = append(stmts, b.String())
+	}
+	return stmts
+}
+
+// clusteredSQL 返回 CLUSTERED 或 NONCLUSTERED
+func clusteredSQL(clustered bool) string {
+	if clustered {
+		return "CLUSTERED"
+	}
+	return "NONCLUSTERED"
+}
+
+// indexColumnsSQL 生成索引键列列表
+func indexColumnsSQL(cols []IndexColumn) string {
+	parts := make([]string, len(cols))
+	for i, c := range cols {
+		parts[i] = utils.EscapeIdentifier(c.Name)
+		if c.Descending {
+			parts[i] += " DESC"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// identifierList 生成转义后的标识符列表
+func identifierList(names []string) string {
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = utils.EscapeIdentifier(n)
+	}
+	return strings.Join(parts, ", ")
+}
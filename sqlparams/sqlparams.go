@@ -0,0 +1,277 @@
+// Package sqlparams 解析 --param/--out-param 指定的类型化查询参数，并通过 sp_executesql 将其绑定到查询
+// 参数值始终作为驱动参数发送，不会拼接到查询文本中
+package sqlparams
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-sql/civil"
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// Param 单个类型化查询参数
+type Param struct {
+	Name  string      // 参数名，不含 @
+	Type  string      // 参数类型，如 date、int、nvarchar
+	Decl  string      // sp_executesql 中的参数声明类型，如 DATE、DECIMAL(38,2)
+	Value interface{} // 传给驱动的值
+}
+
+// paramType 参数类型的声明和值解析
+type paramType struct {
+	decl  string
+	parse func(value string) (interface{}, error)
+}
+
+var (
+	namePattern    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	decimalPattern = regexp.MustCompile(`^[+-]?(\d+)(?:\.(\d*))?$`)
+	sizePattern    = regexp.MustCompile(`^\((\d+(,\d+)?|(?i:max))\)$`)
+)
+
+// dateTimeLayouts datetime/datetime2 参数接受的时间格式
+var dateTimeLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+var types = map[string]paramType{
+	"nvarchar": {"NVARCHAR(MAX)", func(v string) (interface{}, error) { return v, nil }},
+	"varchar":  {"VARCHAR(MAX)", func(v string) (interface{}, error) { return mssql.VarChar(v), nil }},
+	"tinyint":  {"TINYINT", parseInt(8, true)},
+	"smallint": {"SMALLINT", parseInt(16, false)},
+	"int":      {"INT", parseInt(32, false)},
+	"bigint":   {"BIGINT", parseInt(64, false)},
+	"float":    {"FLOAT", func(v string) (interface{}, error) { return strconv.ParseFloat(v, 64) }},
+	"bit":      {"BIT", func(v string) (interface{}, error) { return strconv.ParseBool(v) }},
+	"decimal":  {"DECIMAL", nil}, // 声明的精度由值决定，见 parseDecimal
+	"date": {"DATE", func(v string) (interface{}, error) {
+		return civil.ParseDate(v)
+	}},
+	"time": {"TIME(7)", func(v string) (interface{}, error) {
+		// 与 datetime 相同，秒可以省略
+		if t, err := time.Parse("15:04", v); err == nil {
+			return civil.TimeOf(t), nil
+		}
+		return civil.ParseTime(v)
+	}},
+	"datetime": {"DATETIME", func(v string) (interface{}, error) {
+		t, err := parseDateTime(v)
+		return mssql.DateTime1(t), err
+	}},
+	"datetime2": {"DATETIME2(7)", func(v string) (interface{}, error) {
+		t, err := parseDateTime(v)
+		return civil.DateTimeOf(t), err
+	}},
+	"datetimeoffset": {"DATETIMEOFFSET(7)", func(v string) (interface{}, error) {
+		return time.Parse(time.RFC3339Nano, v)
+	}},
+	"uniqueidentifier": {"UNIQUEIDENTIFIER", func(v string) (interface{}, error) {
+		var u mssql.UniqueIdentifier
+		err := u.Scan(v)
+		return u, err
+	}},
+	"varbinary": {"VARBINARY(MAX)", func(v string) (interface{}, error) {
+		return hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(v, "0x"), "0X"))
+	}},
+}
+
+// aliases 类型别名
+var aliases = map[string]string{
+	"string":  "nvarchar",
+	"bool":    "bit",
+	"guid":    "uniqueidentifier",
+	"uuid":    "uniqueidentifier",
+	"numeric": "decimal",
+	"binary":  "varbinary",
+}
+
+// TypeNames 返回支持的参数类型名（不含别名），用于帮助信息
+func TypeNames() []string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse 解析 --param 参数，每个参数形如 名称=值[:类型]，类型缺省为 nvarchar
+// 值中可以包含冒号，只有最后一个冒号之后是已知类型名时才视为类型
+func Parse(specs []string) ([]Param, error) {
+	var params []Param
+	seen := map[string]bool{}
+	for _, s := range specs {
+		name, value, ok := strings.Cut(s, "=")
+		name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("无效的查询参数 %q，格式应为 名称=值[:类型]", s)
+		}
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("无效的参数名 %q，只能包含字母、数字和下划线，且不能以数字开头", name)
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			return nil, fmt.Errorf("参数 @%s 重复指定", name)
+		}
+		seen[key] = true
+
+		typeName := "nvarchar"
+		if i := strings.LastIndex(value, ":"); i >= 0 {
+			if t, ok := lookupType(value[i+1:]); ok {
+				value, typeName = value[:i], t
+			}
+		}
+
+		p, err := newParam(name, typeName, value)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// ParseOutputs 解析 --out-param 参数，每个参数形如 名称[:类型]，类型缺省为 nvarchar
+// 类型后可以带长度或精度（如 decimal(18,2)、nvarchar(100)），否则使用类型的默认声明
+func ParseOutputs(specs []string, inputs []Param) ([]Param, error) {
+	var params []Param
+	seen := map[string]bool{}
+	for _, p := range inputs {
+		seen[strings.ToLower(p.Name)] = true
+	}
+	for _, s := range specs {
+		name, typeSpec, _ := strings.Cut(s, ":")
+		name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("无效的输出参数 %q，格式应为 名称[:类型]", s)
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			return nil, fmt.Errorf("参数 @%s 重复指定", name)
+		}
+		seen[key] = true
+
+		typeName, size := strings.TrimSpace(typeSpec), ""
+		if i := strings.Index(typeName, "("); i >= 0 && strings.HasSuffix(typeName, ")") {
+			typeName, size = typeName[:i], strings.ReplaceAll(typeName[i:], " ", "")
+			if !sizePattern.MatchString(size) {
+				return nil, fmt.Errorf("输出参数 @%s 的类型长度无效: %s", name, size)
+			}
+		}
+		if typeName == "" {
+			typeName = "nvarchar"
+		}
+		t, ok := lookupType(typeName)
+		if !ok {
+			return nil, fmt.Errorf("输出参数 @%s 的类型 %s 无效，可用类型: %s", name, typeName, strings.Join(TypeNames(), ", "))
+		}
+
+		p := Param{Name: name, Type: t, Decl: types[t].decl}
+		switch {
+		case size != "":
+			p.Decl = strings.ToUpper(t) + strings.ToUpper(size)
+		case t == "decimal":
+			p.Decl = "DECIMAL(38,10)"
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// lookupType 查找类型名（不区分大小写，支持别名）
+func lookupType(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+	_, ok := types[name]
+	return name, ok
+}
+
+// newParam 按类型解析参数值
+func newParam(name, typeName, value string) (Param, error) {
+	p := Param{Name: name, Type: typeName, Decl: types[typeName].decl}
+	var err error
+	if typeName == "decimal" {
+		p.Decl, p.Value, err = parseDecimal(value)
+	} else {
+		p.Value, err = types[typeName].parse(value)
+	}
+	if err != nil {
+		return Param{}, fmt.Errorf("参数 @%s 的值 %q 不是有效的 %s: %w", name, value, typeName, err)
+	}
+	return p, nil
+}
+
+// parseInt 返回指定位数的整数解析函数
+func parseInt(bits int, unsigned bool) func(string) (interface{}, error) {
+	return func(v string) (interface{}, error) {
+		if unsigned {
+			n, err := strconv.ParseUint(v, 10, bits)
+			return uint8(n), err
+		}
+		n, err := strconv.ParseInt(v, 10, bits)
+		switch bits {
+		case 16:
+			return int16(n), err
+		case 32:
+			return int32(n), err
+		}
+		return n, err
+	}
+}
+
+// parseDecimal 校验十进制数并按小数位数确定声明的精度
+// 值以字符串发送，由 SQL Server 转换为 DECIMAL，避免经过浮点数损失精度
+func parseDecimal(v string) (string, interface{}, error) {
+	m := decimalPattern.FindStringSubmatch(v)
+	if m == nil {
+		return "", nil, fmt.Errorf("格式应为 [-]整数[.小数]")
+	}
+	scale := len(m[2])
+	if len(m[1])+scale > 38 {
+		return "", nil, fmt.Errorf("超过 38 位精度")
+	}
+	return fmt.Sprintf("DECIMAL(38,%d)", scale), v, nil
+}
+
+// parseDateTime 按支持的格式解析不带时区的日期时间
+func parseDateTime(v string) (time.Time, error) {
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("格式应为 YYYY-MM-DD[ hh:mm[:ss[.fffffff]]]")
+}
+
+// Bind 将查询改写为 sp_executesql 调用，返回改写后的查询及对应的驱动参数
+// 查询文本本身作为参数发送，其中的 @名称 由 sp_executesql 按声明的类型绑定
+func Bind(query string, params []Param) (string, []interface{}) {
+	if len(params) == 0 {
+		return query, nil
+	}
+
+	decls := make([]string, len(params))
+	assigns := make([]string, len(params))
+	args := make([]interface{}, 0, len(params)+2)
+	args = append(args, query, "")
+	for i, p := range params {
+		decls[i] = "@" + p.Name + " " + p.Decl
+		assigns[i] = "@" + p.Name + " = ?"
+		args = append(args, p.Value)
+	}
+	args[1] = strings.Join(decls, ", ")
+
+	return "EXEC sp_executesql ?, ?, " + strings.Join(assigns, ", "), args
+}
@@ -0,0 +1,136 @@
+// sqlparams/sqlparams_test.go
+package sqlparams
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang-sql/civil"
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want Param
+	}{
+		{"缺省类型", "s=abc", Param{Name: "s", Type: "nvarchar", Decl: "NVARCHAR(MAX)", Value: "abc"}},
+		{"最后一个冒号之后不是类型", "s=a:b", Param{Name: "s", Type: "nvarchar", Decl: "NVARCHAR(MAX)", Value: "a:b"}},
+		{"最后一个冒号之后是类型", "t=12:30:time", Param{Name: "t", Type: "time", Decl: "TIME(7)", Value: civil.Time{Hour: 12, Minute: 30}}},
+		{"带秒的时间", "t=12:30:15.5:time", Param{Name: "t", Type: "time", Decl: "TIME(7)", Value: civil.Time{Hour: 12, Minute: 30, Second: 15, Nanosecond: 500000000}}},
+		{"显式的 nvarchar", "note=a:int:nvarchar", Param{Name: "note", Type: "nvarchar", Decl: "NVARCHAR(MAX)", Value: "a:int"}},
+		{"类型名和别名不区分大小写", "ok=1:BOOL", Param{Name: "ok", Type: "bit", Decl: "BIT", Value: true}},
+		{"@ 前缀", "@n=42:int", Param{Name: "n", Type: "int", Decl: "INT", Value: int32(42)}},
+		{"varchar", "v=x:varchar", Param{Name: "v", Type: "varchar", Decl: "VARCHAR(MAX)", Value: mssql.VarChar("x")}},
+		{"date", "d=2024-01-02:date", Param{Name: "d", Type: "date", Decl: "DATE", Value: civil.Date{Year: 2024, Month: 1, Day: 2}}},
+		{"decimal 小数位数", "m=-12.345:decimal", Param{Name: "m", Type: "decimal", Decl: "DECIMAL(38,3)", Value: "-12.345"}},
+		{"decimal 整数", "m=100:numeric", Param{Name: "m", Type: "decimal", Decl: "DECIMAL(38,0)", Value: "100"}},
+		{"decimal 末尾的小数点", "m=5.:decimal", Param{Name: "m", Type: "decimal", Decl: "DECIMAL(38,0)", Value: "5."}},
+		{"decimal 38 位", "m=" + strings.Repeat("9", 30) + "." + strings.Repeat("1", 8) + ":decimal",
+			Param{Name: "m", Type: "decimal", Decl: "DECIMAL(38,8)", Value: strings.Repeat("9", 30) + "." + strings.Repeat("1", 8)}},
+		{"varbinary", "b=0xCAFE:binary", Param{Name: "b", Type: "varbinary", Decl: "VARBINARY(MAX)", Value: []byte{0xca, 0xfe}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]string{tt.spec})
+			if err != nil {
+				t.Fatalf("意外错误: %v", err)
+			}
+			if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+				t.Errorf("Parse(%q) = %#v，期望 %#v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		wantErr string
+	}{
+		{"缺少等号", []string{"abc"}, "格式应为 名称=值[:类型]"},
+		{"无效的参数名", []string{"1a=x"}, "无效的参数名"},
+		{"重复的参数名", []string{"a=1", "A=2"}, "参数 @A 重复指定"},
+		{"带 @ 的重复参数名", []string{"a=1", "@a=2"}, "重复指定"},
+		{"无效的整数", []string{"n=abc:int"}, "不是有效的 int"},
+		{"tinyint 越界", []string{"n=256:tinyint"}, "不是有效的 tinyint"},
+		{"decimal 超过 38 位", []string{"m=" + strings.Repeat("1", 30) + "." + strings.Repeat("1", 9) + ":decimal"}, "超过 38 位精度"},
+		{"decimal 格式", []string{"m=1e5:decimal"}, "格式应为 [-]整数[.小数]"},
+		{"无效的时间", []string{"t=25:00:time"}, "不是有效的 time"},
+		{"无效的日期时间", []string{"d=2024/01/02:datetime"}, "格式应为 YYYY-MM-DD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.specs)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("错误 = %v，期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseOutputs(t *testing.T) {
+	inputs := []Param{{Name: "Region"}}
+	tests := []struct {
+		spec     string
+		wantType string
+		wantDecl string
+		wantErr  string
+	}{
+		{spec: "msg", wantType: "nvarchar", wantDecl: "NVARCHAR(MAX)"},
+		{spec: "msg:nvarchar(200)", wantType: "nvarchar", wantDecl: "NVARCHAR(200)"},
+		{spec: "msg:varchar(max)", wantType: "varchar", wantDecl: "VARCHAR(MAX)"},
+		{spec: "total:decimal", wantType: "decimal", wantDecl: "DECIMAL(38,10)"},
+		{spec: "total:numeric( 18, 2 )", wantType: "decimal", wantDecl: "DECIMAL(18,2)"},
+		{spec: "@n:int", wantType: "int", wantDecl: "INT"},
+		{spec: "msg:nvarchar(abc)", wantErr: "类型长度无效: (abc)"},
+		{spec: "msg:nvarchar(1,2,3)", wantErr: "类型长度无效"},
+		{spec: "msg:text", wantErr: "类型 text 无效"},
+		{spec: "1msg", wantErr: "无效的输出参数"},
+		{spec: "region:int", wantErr: "参数 @region 重复指定"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseOutputs([]string{tt.spec}, inputs)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误 = %v，期望包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("意外错误: %v", err)
+			}
+			if len(got) != 1 || got[0].Type != tt.wantType || got[0].Decl != tt.wantDecl {
+				t.Errorf("ParseOutputs(%q) = %+v，期望类型 %s、声明 %s", tt.spec, got, tt.wantType, tt.wantDecl)
+			}
+		})
+	}
+
+	if _, err := ParseOutputs([]string{"a", "A:int"}, nil); err == nil || !strings.Contains(err.Error(), "重复指定") {
+		t.Errorf("重复的输出参数: 错误 = %v", err)
+	}
+}
+
+func TestBind(t *testing.T) {
+	query := "SELECT * FROM t WHERE a = @a AND b = @b"
+	if got, args := Bind(query, nil); got != query || args != nil {
+		t.Errorf("没有参数时 Bind = %q, %v，期望原样返回", got, args)
+	}
+
+	params, err := Parse([]string{"a=1:int", "b=x"})
+	if err != nil {
+		t.Fatalf("意外错误: %v", err)
+	}
+	got, args := Bind(query, params)
+	if want := "EXEC sp_executesql ?, ?, @a = ?, @b = ?"; got != want {
+		t.Errorf("Bind 查询 = %q，期望 %q", got, want)
+	}
+	wantArgs := []interface{}{query, "@a INT, @b NVARCHAR(MAX)", int32(1), "x"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Bind 参数 = %#v，期望 %#v", args, wantArgs)
+	}
+}
@@ -0,0 +1,93 @@
+// Package sqlscript 解析 sqlcmd 风格的SQL脚本：处理 :setvar 变量和 $(变量) 引用，并按 GO 分隔符拆分批次
+package sqlscript
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mssql_ie/hooks"
+)
+
+var (
+	// setvarPattern 匹配 :setvar 名称 [值]，值可以用双引号包裹
+	setvarPattern  = regexp.MustCompile(`(?i)^\s*:setvar(?:\s+(\S+)(?:\s+(.*?))?)?\s*$`)
+	varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	varRefPattern  = regexp.MustCompile(`\$\(([^)\s]*)\)`)
+	commandPattern = regexp.MustCompile(`^\s*:[A-Za-z]+`)
+)
+
+// Parse 处理脚本中的 :setvar 命令和 $(变量) 引用，并按 GO 分隔符拆分为批次，空批次会被忽略
+// 变量名不区分大小写，必须在引用之前定义；:setvar 名称 不带值时删除该变量。
+// 不支持 :r、:connect 等其他 sqlcmd 命令，遇到时返回错误。
+// 与 sqlcmd 相同，sqlcmd 命令和变量引用按行识别，不区分是否位于 /* */ 注释或跨行的字符串常量中：
+// 注释或字符串中以 :setvar 开头的行同样会定义变量，以 :r 等开头的行会报错，$(名称) 也会被替换
+func Parse(script string) ([]string, error) {
+	vars := map[string]string{}
+	var b strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if m := setvarPattern.FindStringSubmatch(line); m != nil {
+			if err := setvar(vars, m[1], m[2]); err != nil {
+				return nil, fmt.Errorf("第 %d 行: %w", n, err)
+			}
+			continue
+		}
+		if cmd := commandPattern.FindString(line); cmd != "" {
+			return nil, fmt.Errorf("第 %d 行: 不支持的 sqlcmd 命令 %s", n, strings.TrimSpace(cmd))
+		}
+
+		line, err := expand(line, vars)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", n, err)
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取SQL脚本失败: %w", err)
+	}
+
+	return hooks.SplitBatches(b.String()), nil
+}
+
+// setvar 定义或删除变量
+func setvar(vars map[string]string, name, value string) error {
+	if name == "" {
+		return fmt.Errorf(":setvar 缺少变量名")
+	}
+	if !varNamePattern.MatchString(name) {
+		return fmt.Errorf("无效的变量名 %q", name)
+	}
+	key := strings.ToLower(name)
+	if value == "" {
+		delete(vars, key)
+		return nil
+	}
+	if strings.HasPrefix(value, `"`) {
+		if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+			return fmt.Errorf("变量 %s 的值缺少结束引号", name)
+		}
+		// 与 sqlcmd 相同，引号内的两个双引号表示一个双引号
+		value = strings.ReplaceAll(value[1:len(value)-1], `""`, `"`)
+	}
+	vars[key] = value
+	return nil
+}
+
+// expand 替换一行中的 $(变量) 引用
+func expand(line string, vars map[string]string) (string, error) {
+	var err error
+	line = varRefPattern.ReplaceAllStringFunc(line, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		value, ok := vars[strings.ToLower(name)]
+		if !ok && err == nil {
+			err = fmt.Errorf("变量 %s 未定义", name)
+		}
+		return value
+	})
+	return line, err
+}
@@ -0,0 +1,85 @@
+// sqlscript/sqlscript_test.go
+package sqlscript
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "定义和引用变量",
+			script: ":setvar Days 30\nSELECT * FROM t WHERE d > DATEADD(day, -$(days), GETDATE())\n",
+			want:   []string{"SELECT * FROM t WHERE d > DATEADD(day, -30, GETDATE())\n"},
+		},
+		{
+			name:   "引号包裹的值",
+			script: ":setvar Name \"O\"\"Brien and co\"\nSELECT '$(Name)'\n",
+			want:   []string{"SELECT 'O\"Brien and co'\n"},
+		},
+		{
+			name:   "重新定义变量",
+			script: ":setvar t a\nSELECT $(t)\nGO\n:setvar t b\nSELECT $(t)\n",
+			want:   []string{"SELECT a\n", "\nSELECT b\n"}, // :setvar 行被去掉，GO 后的换行保留在下一批次
+		},
+		{
+			name:    "删除变量后引用",
+			script:  ":setvar t a\n:setvar t\nSELECT $(t)\n",
+			wantErr: "第 3 行: 变量 t 未定义",
+		},
+		{
+			name:    "引用未定义的变量",
+			script:  "SELECT 1\nSELECT $(x)\n",
+			wantErr: "第 2 行: 变量 x 未定义",
+		},
+		{
+			name:    "值缺少结束引号",
+			script:  ":setvar Name \"abc\n",
+			wantErr: "缺少结束引号",
+		},
+		{
+			name:    "无效的变量名",
+			script:  ":setvar 1x a\n",
+			wantErr: "无效的变量名",
+		},
+		{
+			name:    "不支持的命令",
+			script:  "SELECT 1\n:r other.sql\n",
+			wantErr: "第 2 行: 不支持的 sqlcmd 命令 :r",
+		},
+		{
+			name:   "按 GO 拆分并忽略空批次",
+			script: "CREATE TABLE #t (id int)\nGO\n\ngo\nINSERT #t VALUES (1)\nGO\nSELECT * FROM #t\n",
+			want:   []string{"CREATE TABLE #t (id int)\n", "\nINSERT #t VALUES (1)\n", "\nSELECT * FROM #t\n"},
+		},
+		{
+			name:   "字符串中的 GO 不拆分",
+			script: "SELECT 'a\nGO\nb'\n",
+			want:   []string{"SELECT 'a\nGO\nb'\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.script)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误 = %v，期望包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("意外错误: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("批次 = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
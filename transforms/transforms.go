@@ -0,0 +1,358 @@
+// Package transforms 实现导入导出时按列应用的值转换（如 trim、upper、正则替换、日期格式转换）
+package transforms
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Func 单个转换函数
+type Func func(value string) (string, error)
+
+// step 转换链中的一步
+type step struct {
+	name string
+	fn   Func
+}
+
+// Chain 作用于单个列的转换链，按顺序依次执行
+type Chain struct {
+	Column string
+	Spec   string
+	steps  []step
+}
+
+// Apply 依次执行转换链中的所有函数
+func (c *Chain) Apply(value string) (string, error) {
+	var err error
+	for _, s := range c.steps {
+		if value, err = s.fn(value); err != nil {
+			return "", fmt.Errorf("列 %s 的转换 %s 失败: %w", c.Column, s.name, err)
+		}
+	}
+	return value, nil
+}
+
+// Set 按列名（不区分大小写）索引的转换链集合
+type Set map[string]*Chain
+
+// For 返回指定列的转换链，未配置时返回 nil
+func (s Set) For(column string) *Chain {
+	if s == nil {
+		return nil
+	}
+	return s[strings.ToLower(column)]
+}
+
+// Check 检查所有配置了转换的列都存在于 columns 中
+func (s Set) Check(columns []string) error {
+	known := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		known[strings.ToLower(c)] = true
+	}
+	var missing []string
+	for key, chain := range s {
+		if !known[key] {
+			missing = append(missing, chain.Column)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("转换配置中的列不存在: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// fileConfig 转换配置文件格式
+//
+//	{"transforms": {"name": "trim|upper", "active": "map:Y=1,N=0"}}
+type fileConfig struct {
+	Transforms map[string]string `json:"transforms"`
+}
+
+// Load 解析 --transform 参数和转换配置文件，命令行参数覆盖配置文件中同名列的设置
+// 每个参数形如 col=fn1|fn2:arg1:arg2；参数中的 |、: 和 \ 需要用 \ 转义
+func Load(specs []string, file string) (Set, error) {
+	set := Set{}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取转换配置文件失败: %w", err)
+		}
+		var cfg fileConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("解析转换配置文件 %s 失败: %w", file, err)
+		}
+		for column, spec := range cfg.Transforms {
+			if err := set.add(column, spec); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, s := range specs {
+		column, spec, ok := strings.Cut(s, "=")
+		if !ok || strings.TrimSpace(column) == "" {
+			return nil, fmt.Errorf("无效的转换参数 %q，格式应为 列名=函数1|函数2", s)
+		}
+		if err := set.add(column, spec); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(set) == 0 {
+		return nil, nil
+	}
+	return set, nil
+}
+
+// add 解析并添加一个列的转换链
+func (s Set) add(column, spec string) error {
+	column = strings.TrimSpace(column)
+	chain := &Chain{Column: column, Spec: spec}
+	for _, part := range splitEscaped(spec, '|', false) {
+		args := splitEscaped(part, ':', true)
+		name := strings.ToLower(strings.TrimSpace(args[0]))
+		if name == "" {
+			continue
+		}
+		builder, ok := library[name]
+		if !ok {
+			return fmt.Errorf("列 %s 使用了未知的转换函数 %s，可用函数: %s", column, name, strings.Join(Names(), ", "))
+		}
+		fn, err := builder(args[1:])
+		if err != nil {
+			return fmt.Errorf("列 %s 的转换函数 %s 参数无效: %w", column, name, err)
+		}
+		chain.steps = append(chain.steps, step{name: name, fn: fn})
+	}
+	if len(chain.steps) == 0 {
+		return fmt.Errorf("列 %s 没有配置转换函数", column)
+	}
+	s[strings.ToLower(column)] = chain
+	return nil
+}
+
+// splitEscaped 按分隔符拆分字符串，\ 转义其后的字符
+// unescape 为 false 时保留转义符，供后续再次拆分
+func splitEscaped(s string, sep rune, unescape bool) []string {
+	var parts []string
+	var cur strings.Builder
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			if !unescape {
+				cur.WriteRune('\\')
+			}
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == sep:
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if escaped {
+		cur.WriteRune('\\')
+	}
+	return append(parts, cur.String())
+}
+
+// builder 根据参数构造转换函数
+type builder func(args []string) (Func, error)
+
+// library 内置转换函数
+var library = map[string]builder{
+	"trim":  noArgs(func(v string) (string, error) { return strings.TrimSpace(v), nil }),
+	"ltrim": noArgs(func(v string) (string, error) { return strings.TrimLeft(v, " \t\r\n"), nil }),
+	"rtrim": noArgs(func(v string) (string, error) { return strings.TrimRight(v, " \t\r\n"), nil }),
+	"upper": noArgs(func(v string) (string, error) { return strings.ToUpper(v), nil }),
+	"lower": noArgs(func(v string) (string, error) { return strings.ToLower(v), nil }),
+	// replace:旧值:新值 替换所有匹配的子串
+	"replace": func(args []string) (Func, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("用法 replace:旧值:新值")
+		}
+		return func(v string) (string, error) { return strings.ReplaceAll(v, args[0], args[1]), nil }, nil
+	},
+	// regex:表达式:替换 正则替换，替换中可使用 $1 引用分组
+	"regex": func(args []string) (Func, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("用法 regex:表达式:替换")
+		}
+		re, err := regexp.Compile(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return func(v string) (string, error) { return re.ReplaceAllString(v, args[1]), nil }, nil
+	},
+	// map:Y=1,N=0[,*=默认值] 按值映射，未匹配且未指定 * 时报错
+	"map": func(args []string) (Func, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("用法 map:源值=目标值,源值=目标值[,*=默认值]")
+		}
+		mapping := map[string]string{}
+		for _, pair := range splitEscaped(args[0], ',', true) {
+			from, to, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("无效的映射 %q", pair)
+			}
+			mapping[from] = to
+		}
+		return func(v string) (string, error) {
+			if to, ok := mapping[v]; ok {
+				return to, nil
+			}
+			if to, ok := mapping["*"]; ok {
+				return to, nil
+			}
+			return "", fmt.Errorf("值 %q 没有对应的映射", v)
+		}, nil
+	},
+	// date:输入格式[:输出格式] 按输入格式解析日期，默认输出 yyyy-MM-dd 或 yyyy-MM-dd HH:mm:ss
+	"date": func(args []string) (Func, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return nil, fmt.Errorf("用法 date:输入格式[:输出格式]")
+		}
+		in, err := goLayout(args[0])
+		if err != nil {
+			return nil, err
+		}
+		out := "2006-01-02"
+		if strings.ContainsAny(args[0], "Hhms") {
+			out = "2006-01-02 15:04:05"
+		}
+		if len(args) == 2 {
+			if out, err = goLayout(args[1]); err != nil {
+				return nil, err
+			}
+		}
+		return dateFunc([]string{in}, out), nil
+	},
+	// datefmt:输出格式 解析常见的 ISO 格式日期并按指定格式输出，用于导出
+	"datefmt": func(args []string) (Func, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("用法 datefmt:输出格式")
+		}
+		out, err := goLayout(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return dateFunc(isoLayouts, out), nil
+	},
+	// default:值 空值替换为指定值
+	"default": func(args []string) (Func, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("用法 default:值")
+		}
+		return func(v string) (string, error) {
+			if v == "" {
+				return args[0], nil
+			}
+			return v, nil
+		}, nil
+	},
+	// nullif:值 等于指定值时替换为空值（导入时为 NULL）
+	"nullif": func(args []string) (Func, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("用法 nullif:值")
+		}
+		return func(v string) (string, error) {
+			if v == args[0] {
+				return "", nil
+			}
+			return v, nil
+		}, nil
+	},
+}
+
+// Names 返回所有内置转换函数名
+func Names() []string {
+	names := make([]string, 0, len(library))
+	for name := range library {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// noArgs 包装不需要参数的转换函数
+func noArgs(fn Func) builder {
+	return func(args []string) (Func, error) {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("不接受参数")
+		}
+		return fn, nil
+	}
+}
+
+// isoLayouts datefmt 可识别的输入格式，与导出的日期时间格式一致
+// （解析时秒之后的小数部分可省略或为任意位数）
+var isoLayouts = []string{
+	"2006-01-02 15:04:05.000",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02",
+	"15:04:05",
+}
+
+// dateFunc 按候选格式解析日期并按输出格式格式化，空值保持不变
+func dateFunc(layouts []string, out string) Func {
+	return func(v string) (string, error) {
+		if v == "" {
+			return v, nil
+		}
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t.Format(out), nil
+			}
+		}
+		return "", fmt.Errorf("无法解析日期 %q", v)
+	}
+}
+
+// layoutTokens 日期格式占位符到 Go 时间格式的映射，按长度从长到短匹配
+var layoutTokens = []struct{ token, layout string }{
+	{"yyyy", "2006"}, {"fff", "000"}, {"MM", "01"}, {"dd", "02"},
+	{"HH", "15"}, {"mm", "04"}, {"ss", "05"}, {"yy", "06"},
+}
+
+// goLayout 将 yyyyMMdd 形式的日期格式转换为 Go 时间格式
+// Go 时间格式没有转义，占位符之外的数字和字母（如 1、5、Jan、PM）会被当作格式元素，
+// 因此字面字符只能是标点、空白和 T；fff 只能跟在 . 或 , 之后
+func goLayout(format string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(format); {
+		matched := false
+		for _, t := range layoutTokens {
+			if strings.HasPrefix(format[i:], t.token) {
+				if t.token == "fff" && (i == 0 || (format[i-1] != '.' && format[i-1] != ',')) {
+					return "", fmt.Errorf("日期格式 %q 中的 fff 必须跟在 . 或 , 之后", format)
+				}
+				b.WriteString(t.layout)
+				i += len(t.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			c := format[i]
+			if c >= '0' && c <= '9' || (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') && c != 'T' {
+				return "", fmt.Errorf("日期格式 %q 中的字符 %q 不是占位符，字面字符不能是数字或字母（T 除外）", format, c)
+			}
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String(), nil
+}
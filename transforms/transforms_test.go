@@ -0,0 +1,116 @@
+// transforms/transforms_test.go
+package transforms
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitEscaped(t *testing.T) {
+	tests := []struct {
+		s        string
+		sep      rune
+		unescape bool
+		want     []string
+	}{
+		{`a|b|c`, '|', false, []string{"a", "b", "c"}},
+		{`a\|b|c`, '|', false, []string{`a\|b`, "c"}},
+		{`a\|b|c`, '|', true, []string{"a|b", "c"}},
+		{`replace:\::-`, ':', true, []string{"replace", ":", "-"}},
+		{`a\\|b`, '|', true, []string{`a\`, "b"}},
+		{`a\`, '|', true, []string{`a\`}},
+		{``, '|', true, []string{""}},
+	}
+	for _, tt := range tests {
+		if got := splitEscaped(tt.s, tt.sep, tt.unescape); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitEscaped(%q, %q, %v) = %q，期望 %q", tt.s, tt.sep, tt.unescape, got, tt.want)
+		}
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{name: "trim", spec: "trim", in: "  a b \t", want: "a b"},
+		{name: "ltrim", spec: "ltrim", in: "  a ", want: "a "},
+		{name: "rtrim", spec: "rtrim", in: "  a \r\n", want: "  a"},
+		{name: "upper", spec: "upper", in: "abc", want: "ABC"},
+		{name: "lower", spec: "lower", in: "ABC", want: "abc"},
+		{name: "函数链按顺序执行", spec: "trim|upper", in: " ab ", want: "AB"},
+		{name: "replace", spec: "replace:-:/", in: "2024-01-02", want: "2024/01/02"},
+		{name: "replace 转义的冒号", spec: `replace:\::.`, in: "12:30", want: "12.30"},
+		{name: "replace 转义的竖线", spec: `replace:\|:,|upper`, in: "a|b", want: "A,B"},
+		{name: "regex 转义的反斜杠", spec: `regex:(\\d+)-(\\d+):$2-$1`, in: "12-34", want: "34-12"},
+		{name: "map", spec: "map:Y=1,N=0", in: "N", want: "0"},
+		{name: "map 转义的逗号", spec: `map:a\\,b=1,c=2`, in: "a,b", want: "1"},
+		{name: "map 默认值", spec: "map:Y=1,*=0", in: "X", want: "0"},
+		{name: "map 未匹配", spec: "map:Y=1", in: "X", wantErr: "没有对应的映射"},
+		{name: "date 默认输出", spec: "date:yyyyMMdd", in: "20240102", want: "2024-01-02"},
+		{name: "date 含时间", spec: "date:dd/MM/yyyy HH\\:mm", in: "02/01/2024 13:45", want: "2024-01-02 13:45:00"},
+		{name: "date 指定输出", spec: "date:yyyyMMdd:dd.MM.yy", in: "20240102", want: "02.01.24"},
+		{name: "date 毫秒和 T", spec: "date:yyyy-MM-ddTHH\\:mm\\:ss.fff:yyyyMMddHHmmss", in: "2024-01-02T13:45:06.789", want: "20240102134506"},
+		{name: "date 空值不变", spec: "date:yyyyMMdd", in: "", want: ""},
+		{name: "date 无法解析", spec: "date:yyyyMMdd", in: "2024-01-02", wantErr: "无法解析日期"},
+		{name: "datefmt", spec: "datefmt:dd/MM/yyyy", in: "2024-01-02 13:45:06.123", want: "02/01/2024"},
+		{name: "datefmt ISO", spec: "datefmt:yyyy-MM-dd HH\\:mm", in: "2024-01-02T13:45:06+08:00", want: "2024-01-02 13:45"},
+		{name: "default", spec: "default:N/A", in: "", want: "N/A"},
+		{name: "default 非空值不变", spec: "default:N/A", in: "x", want: "x"},
+		{name: "nullif", spec: "nullif:NULL", in: "NULL", want: ""},
+		{name: "nullif 不相等", spec: "nullif:NULL", in: "null", want: "null"},
+		{name: "nullif 转义的竖线", spec: `nullif:\|`, in: "|", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := Load([]string{"c=" + tt.spec}, "")
+			if err != nil {
+				t.Fatalf("解析 %q 失败: %v", tt.spec, err)
+			}
+			got, err := set.For("C").Apply(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误 = %v，期望包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("意外错误: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("%s(%q) = %q，期望 %q", tt.spec, tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr string
+	}{
+		{"c", "格式应为"},
+		{"c=", "没有配置转换函数"},
+		{"c=nope", "未知的转换函数 nope"},
+		{"c=trim:x", "不接受参数"},
+		{"c=replace:a", "用法 replace"},
+		{"c=regex:(:x", "参数无效"},
+		{"c=map:Y", "无效的映射"},
+		{"c=date", "用法 date"},
+		{"c=date:yyyy1MMdd", `字符 '1' 不是占位符`},
+		{"c=date:yyyyMMdd:dd.MM.2yy", `字符 '2' 不是占位符`},
+		{"c=date:yyyy-MM-dd hh", `字符 'h' 不是占位符`},
+		{"c=datefmt:dd Mon yyyy", `字符 'M' 不是占位符`},
+		{"c=datefmt:HH\\:mm\\:ssfff", "fff 必须跟在 . 或 , 之后"},
+	}
+	for _, tt := range tests {
+		_, err := Load([]string{tt.spec}, "")
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Load(%q) 错误 = %v，期望包含 %q", tt.spec, err, tt.wantErr)
+		}
+	}
+}
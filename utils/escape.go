@@ -55,6 +55,23 @@ func EscapeQualifiedName(name string) (string, error) {
 	return strings.Join(escapedParts, "."), nil
 }
 
+// SplitQualifiedName 将表名拆分为架构名和对象名，不带架构时默认为 dbo
+// 支持格式: table, schema.table, [schema].[table]
+func SplitQualifiedName(name string) (string, string, error) {
+	parts, err := parseQualifiedName(strings.TrimSpace(name))
+	if err != nil {
+		return "", "", err
+	}
+	switch len(parts) {
+	case 1:
+		return "dbo", parts[0], nil
+	case 2:
+		return parts[0], parts[1], nil
+	default:
+		return "", "", newEscapeError("表名必须为 table 或 schema.table 格式")
+	}
+}
+
 // parseQualifiedName 解析限定名
 // 处理: table, schema.table, [schema].[table], [schema.table]
 func parseQualifiedName(name string) ([]string, error) {